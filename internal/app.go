@@ -38,7 +38,14 @@ func buildApp() *cli.App {
 			listCommand(),
 			syncCommand(),
 			whichCommand(),
+			searchCommand(),
 			componentCommand(),
+			runCommand(),
+			psCommand(),
+			logsCommand(),
+			killCommand(),
+			completionCommand(),
+			completeCommand(),
 		},
 	}
 }
@@ -81,6 +88,18 @@ func globalFlags() []cli.Flag {
 			Aliases: []string{"r"},
 			Usage:   "Path to the recipe file (note: additional recipe flags not supported)",
 		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Fail on templates that reference undefined variables",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print commands and template exec calls instead of running them",
+		},
+		&cli.BoolFlag{
+			Name:  "forget",
+			Usage: "Clear remembered prompt answers for the recipe before running it",
+		},
 	}
 }
 
@@ -215,6 +234,50 @@ func whichCommand() *cli.Command {
 	}
 }
 
+// searchCommand defines the 'search' command
+func searchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "search",
+		Usage:     "Search recipes by name, category, description, help, author and commands",
+		ArgsUsage: "terms...",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "local",
+				Aliases: []string{"l"},
+				Usage:   "Filter to local recipes only",
+			},
+			&cli.BoolFlag{
+				Name:    "user",
+				Aliases: []string{"u"},
+				Usage:   "Filter to user recipes only",
+			},
+			&cli.BoolFlag{
+				Name:    "public",
+				Aliases: []string{"p"},
+				Usage:   "Filter to public recipes only",
+			},
+			&cli.StringFlag{
+				Name:    "category",
+				Aliases: []string{"c"},
+				Usage:   "Filter by category",
+			},
+			&cli.BoolFlag{
+				Name:    "json",
+				Aliases: []string{"j"},
+				Usage:   "Output results in JSON format",
+			},
+			&cli.BoolFlag{
+				Name:    "interactive",
+				Aliases: []string{"i"},
+				Usage:   "Pick a matching recipe and run it",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return handleSearchCommand(c, getSourcePriority(c))
+		},
+	}
+}
+
 // componentCommand defines the 'run-component' command
 func componentCommand() *cli.Command {
 	return &cli.Command{
@@ -234,3 +297,102 @@ func componentCommand() *cli.Command {
 		},
 	}
 }
+
+// runCommand defines the 'run' command
+func runCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "run",
+		Usage:     "Run a recipe, optionally detached from the terminal",
+		ArgsUsage: "[category] recipe_name [input] [options]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "detach",
+				Aliases: []string{"D"},
+				Usage:   "Run the recipe in the background and print its run id",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return handleRunCommand(c)
+		},
+	}
+}
+
+// psCommand defines the 'ps' command
+func psCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "ps",
+		Usage:           "List running and recent detached recipe runs",
+		SkipFlagParsing: true,
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "json",
+				Aliases: []string{"j"},
+				Usage:   "Output results in JSON format",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return handlePsCommand(c)
+		},
+	}
+}
+
+// logsCommand defines the 'logs' command
+func logsCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "logs",
+		Usage:           "Show the output of a detached recipe run",
+		SkipFlagParsing: true,
+		HideHelpCommand: true,
+		ArgsUsage:       "run_id",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "follow",
+				Aliases: []string{"f"},
+				Usage:   "Keep streaming output until the run finishes",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return handleLogsCommand(c)
+		},
+	}
+}
+
+// killCommand defines the 'kill' command
+func killCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "kill",
+		Usage:           "Terminate a detached recipe run and its child processes",
+		SkipFlagParsing: true,
+		HideHelpCommand: true,
+		ArgsUsage:       "run_id",
+		Action: func(c *cli.Context) error {
+			return handleKillCommand(c)
+		},
+	}
+}
+
+// completionCommand defines the 'completion' command
+func completionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "completion",
+		Usage:     "Print the shell completion script for bash, zsh or fish",
+		ArgsUsage: "bash|zsh|fish",
+		Action: func(c *cli.Context) error {
+			return handleCompletionCommand(c)
+		},
+	}
+}
+
+// completeCommand defines the hidden '__complete' command called by the completion scripts
+func completeCommand() *cli.Command {
+	return &cli.Command{
+		Name:            "__complete",
+		Hidden:          true,
+		SkipFlagParsing: true,
+		HideHelpCommand: true,
+		Action: func(c *cli.Context) error {
+			return handleCompleteCommand(c)
+		},
+	}
+}
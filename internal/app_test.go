@@ -1,15 +1,19 @@
 package internal
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/AlecAivazis/survey/v2/terminal"
 	"github.com/agiledragon/gomonkey/v2"
 	"github.com/rogpeppe/go-internal/testscript"
 	"github.com/stretchr/testify/assert"
@@ -198,7 +202,7 @@ func TestLoadFile(t *testing.T) {
 	}
 }
 
-// TestLoadRecipes tests the loadRecipes function
+// TestLoadRecipes tests indexing recipe files and filtering indexed recipes by category
 func TestLoadRecipes(t *testing.T) {
 	testFileData := []byte(testFile)
 	patches := gomonkey.NewPatches()
@@ -211,9 +215,12 @@ func TestLoadRecipes(t *testing.T) {
 		return nil, fmt.Errorf("file not found: %s", filename)
 	})
 
+	entry := indexRecipeFile("test-file.yaml")
+	assert.Empty(t, entry.Error)
+	catalog := &recipeCatalog{recipes: map[string][]recipeSummary{"local": entry.Recipes}}
+
 	t.Run("load all recipes", func(t *testing.T) {
-		recipes, err := loadRecipes([]string{"test-file.yaml"}, "")
-		assert.NoError(t, err)
+		recipes := catalog.sourceRecipes("local", "")
 		assert.Len(t, recipes, 2, "Expected 2 recipes, got %d", len(recipes))
 
 		if len(recipes) >= 2 {
@@ -223,12 +230,12 @@ func TestLoadRecipes(t *testing.T) {
 
 			assert.Equal(t, "another-recipe", recipes[1].Name)
 			assert.Equal(t, "Another test recipe", recipes[1].Description)
+			assert.Equal(t, 1, recipes[1].Position)
 		}
 	})
 
 	t.Run("filter by category", func(t *testing.T) {
-		recipes, err := loadRecipes([]string{"test-file.yaml"}, "test")
-		assert.NoError(t, err)
+		recipes := catalog.sourceRecipes("local", "test")
 		assert.Len(t, recipes, 1, "Expected 1 recipe, got %d", len(recipes))
 
 		if len(recipes) >= 1 {
@@ -237,10 +244,13 @@ func TestLoadRecipes(t *testing.T) {
 	})
 
 	t.Run("no matching category", func(t *testing.T) {
-		recipes, err := loadRecipes([]string{"test-file.yaml"}, "non-existent")
-		assert.NoError(t, err)
+		recipes := catalog.sourceRecipes("local", "non-existent")
 		assert.Len(t, recipes, 0, "Expected 0 recipes, got %d", len(recipes))
 	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		assert.Contains(t, indexRecipeFile("missing.yaml").Error, "file not found")
+	})
 }
 
 // TestFindRecipeSourcesByType tests the findRecipeSourcesByType function
@@ -691,6 +701,117 @@ func TestEvaluateCondition(t *testing.T) {
 			want:      true,
 			wantErr:   false,
 		},
+		{
+			name:      "and binds tighter than or",
+			condition: "$test == false && op1.success || $number == 42",
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "nested parentheses",
+			condition: "($test == true && ($number < 10 || op2.failure)) && !(op1.failure)",
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "quoted string containing operators",
+			condition: `$text == "x || y" || $text == "a && b"`,
+			want:      false,
+			wantErr:   false,
+		},
+		{
+			name:      "quoted string comparison",
+			condition: `.text == 'value'`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "in list",
+			condition: `.text in ["other", "value"]`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "not in list",
+			condition: `.number not in [1, 2, 3]`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "regex match",
+			condition: `.op1 =~ "^out[a-z]+[0-9]$"`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "contains and startsWith",
+			condition: `.text contains "alu" && startsWith(.op1, "out")`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "bare right-hand side is a literal",
+			condition: ".text == number",
+			want:      false,
+			wantErr:   false,
+		},
+		{
+			name:      "bare words in a list are literals",
+			condition: ".text in [test, value]",
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "undefined bare word is false",
+			condition: "undefinedvar",
+			want:      false,
+			wantErr:   false,
+		},
+		{
+			name:      "multi-word right-hand side",
+			condition: ".text != hello world && .text == value",
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "bare function argument is a literal",
+			condition: `hasCommand(definitely_not_a_cmd_xyz) || env(SHEF_UNSET_VARIABLE) != ""`,
+			want:      false,
+			wantErr:   false,
+		},
+		{
+			name:      "variable function argument",
+			condition: `startsWith(.text, val)`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "numeric equality across formats",
+			condition: "$number == 42.0",
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "string ordering",
+			condition: `.text > "abc"`,
+			want:      true,
+			wantErr:   false,
+		},
+		{
+			name:      "unbalanced parentheses",
+			condition: "($test == true",
+			wantErr:   true,
+		},
+		{
+			name:      "unterminated string",
+			condition: `$text == "value`,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown function",
+			condition: `missing(.text)`,
+			wantErr:   true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1458,6 +1579,772 @@ func TestMathTemplateFunctions(t *testing.T) {
 	})
 }
 
+// TestDataTemplateFunctions tests the JSON and YAML template functions
+func TestDataTemplateFunctions(t *testing.T) {
+	vars := map[string]interface{}{
+		"services": `[{"name":"api","team":"core","port":8080},{"name":"web","team":"edge","port":80},{"name":"db","team":"core","port":5432}]`,
+		"config":   "name: shef\nlabels:\n  app: shef\n  tier: cli\n",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"jsonPath field", `{{ jsonPath .services ".[1].name" }}`, "web"},
+		{"jsonPath negative index", `{{ jsonPath .services ".[-1].port" }}`, "5432"},
+		{"jsonPath iterate", `{{ joinArray (jsonPath .services ".[].name") "," }}`, "api,web,db"},
+		{"jsonPath missing", `{{ jsonPath .services ".[0].missing" }}`, "false"},
+		{"pluck", `{{ joinArray (pluck .services "name") "," }}`, "api,web,db"},
+		{"sortBy numeric", `{{ joinArray (pluck (sortBy .services "port") "name") "," }}`, "web,db,api"},
+		{"uniq", `{{ joinArray (uniq (pluck .services "team")) "," }}`, "core,edge"},
+		{"groupBy", `{{ $groups := groupBy .services "team" }}{{ count (index $groups "core") }}`, "2"},
+		{"fromYaml and keys", `{{ joinArray (keys (fromYaml .config).labels) "," }}`, "app,tier"},
+		{"values", `{{ joinArray (values (fromYaml .config).labels) "," }}`, "shef,cli"},
+		{"toJson", `{{ toJson (fromYaml .config).labels }}`, `{"app":"shef","tier":"cli"}`},
+		{"toPrettyJson", `{{ toPrettyJson (list 1 2) }}`, "[\n  1,\n  2\n]"},
+		{"toYaml", `{{ toYaml (jsonPath .services ".[0]") }}`, "name: api\nport: 8080\nteam: core"},
+		{"fromJson index", `{{ (index (fromJson .services) 0).team }}`, "core"},
+		{"fromJson numbers compare", `{{ gt (index (fromJson .services) 0).port 1024 }}`, "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderTemplate(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+// TestStringTemplateFunctions tests the regex, hashing, encoding and path template functions
+func TestStringTemplateFunctions(t *testing.T) {
+	vars := map[string]interface{}{
+		"version": "release-v1.4.2",
+		"name":    "it's a test",
+		"path":    "/var/log/app/server.log",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"regexMatch", `{{ regexMatch .version "v[0-9]+" }}`, "true"},
+		{"regexFind", `{{ regexFind .version "[0-9.]+$" }}`, "1.4.2"},
+		{"regexReplace", `{{ regexReplace .version "release-v(.*)" "$1" }}`, "1.4.2"},
+		{"regexSplit", `{{ joinArray (regexSplit "a1b22c" "[0-9]+") "," }}`, "a,b,c"},
+		{"sha256", `{{ sha256 "shef" }}`, "b0846c4700fc211e6822c8dd6b9fb434e0d58e486793ddcf22c22c9f2e0d8542"},
+		{"md5", `{{ md5 "shef" }}`, "cf7476371d937463ac2cb7c4ba265a55"},
+		{"b64enc", `{{ b64enc "shef" }}`, "c2hlZg=="},
+		{"b64dec", `{{ b64dec "c2hlZg==" }}`, "shef"},
+		{"urlquery", `{{ urlquery "a b&c" }}`, "a+b%26c"},
+		{"upper", `{{ upper "shef" }}`, "SHEF"},
+		{"lower", `{{ lower "SHEF" }}`, "shef"},
+		{"title", `{{ title "hello shef-world" }}`, "Hello Shef-World"},
+		{"indent", `{{ indent "a\nb" 2 }}`, "  a\n  b"},
+		{"wrap", `{{ wrap "one two three four" 9 }}`, "one two\nthree\nfour"},
+		{"quote", `{{ quote .name }}`, `"it's a test"`},
+		{"shellQuote", `{{ shellQuote .name }}`, `'it'\''s a test'`},
+		{"shellQuote safe", `{{ shellQuote "file.txt" }}`, "file.txt"},
+		{"shellQuote empty", `{{ shellQuote "" }}`, "''"},
+		{"shellQuote in echo", `echo {{ shellQuote .name }} {{ shellQuote "b c" }}`, `echo 'it'\''s a test' 'b c'`},
+		{"quoted echo", `echo '{{ .name }}'`, `echo 'it'\''s a test'`},
+		{"base", `{{ base .path }}`, "server.log"},
+		{"dir", `{{ dir .path }}`, "/var/log/app"},
+		{"ext", `{{ ext .path }}`, ".log"},
+		{"absPath", `{{ absPath "/tmp/../tmp/x" }}`, "/tmp/x"},
+		{"uuid", `{{ len uuid }}`, "36"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderTemplate(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+// TestDateTemplateFunctions tests the date and time template functions
+func TestDateTemplateFunctions(t *testing.T) {
+	vars := map[string]interface{}{
+		"started":  "2024-03-01T10:00:00Z",
+		"finished": "2024-03-01T11:30:05Z",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"date strftime", `{{ date "backup-%Y%m%d-%H%M" .started }}`, "backup-20240301-1000"},
+		{"date go layout", `{{ date "2006-01-02" .started }}`, "2024-03-01"},
+		{"date literal percent", `{{ date "%d%%" .started }}`, "01%"},
+		{"unixEpoch", `{{ unixEpoch .started }}`, "1709287200"},
+		{"dateAdd days", `{{ date "%F" (dateAdd "7d" .started) }}`, "2024-03-08"},
+		{"dateAdd pipe", `{{ .started | dateAdd "-90m" | date "%H:%M" }}`, "08:30"},
+		{"dateDiff", `{{ dateDiff .started .finished }}`, "1h30m5s"},
+		{"humanizeDuration", `{{ humanizeDuration (dateDiff .started .finished) }}`, "01:30:05"},
+		{"humanizeDuration seconds", `{{ humanizeDuration 75 }}`, "01:15"},
+		{"humanizeDurationMs", `{{ humanizeDurationMs "1.5s" }}`, "00:01.500"},
+		{"humanizeDuration negative", `{{ humanizeDuration (dateDiff .finished .started) }}`, "-01:30:05"},
+		{"parseDate layout", `{{ date "%F" (parseDate "01/02/2024" "01/02/2006") }}`, "2024-01-02"},
+		{"now", `{{ gt (unixEpoch now) 1700000000 }}`, "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderTemplate(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+// TestSemverFunctions tests the semantic version template functions and condition comparisons
+func TestSemverFunctions(t *testing.T) {
+	vars := map[string]interface{}{
+		"tags": "v1.10.0\nv1.2.0\nnot-a-version\nv1.2.0-rc.1\nv1.9.3",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"semverParse", `{{ $v := semverParse "v2.3.4-beta.1+build.7" }}{{ $v.Major }} {{ $v.Minor }} {{ $v.Patch }} {{ $v.Prerelease }} {{ $v.Build }}`, "2 3 4 beta.1 build.7"},
+		{"semverBump major", `{{ semverBump "major" "v1.4.2" }}`, "v2.0.0"},
+		{"semverBump minor", `{{ semverBump "minor" "1.4.2" }}`, "1.5.0"},
+		{"semverBump patch", `{{ "v1.4.2" | semverBump "patch" }}`, "v1.4.3"},
+		{"semverBump patch releases prerelease", `{{ semverBump "patch" "1.4.3-rc.2" }}`, "1.4.3"},
+		{"semverBump prerelease", `{{ semverBump "prerelease" "1.4.2" }}`, "1.4.3-rc.0"},
+		{"semverBump prerelease increments", `{{ semverBump "prerelease" "1.4.3-rc.0" }}`, "1.4.3-rc.1"},
+		{"semverBump prerelease id", `{{ semverBump "prerelease:beta" "1.4.3-alpha.4" }}`, "1.4.3-beta.0"},
+		{"semverCompare", `{{ semverCompare "v1.10.0" "v1.9.3" }}`, "1"},
+		{"semverCompare prerelease", `{{ semverCompare "1.2.0-rc.1" "1.2.0" }}`, "-1"},
+		{"semverSort", `{{ joinArray (semverSort .tags) "," }}`, "v1.2.0-rc.1,v1.2.0,v1.9.3,v1.10.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderTemplate(tt.template, vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	ctx := &ExecutionContext{
+		Vars:             map[string]interface{}{"current": "v1.10.0", "next": "v1.9.3"},
+		OperationOutputs: map[string]string{},
+		OperationResults: map[string]bool{},
+	}
+
+	conditions := []struct {
+		condition string
+		want      bool
+	}{
+		{`semver(.current) > semver(.next)`, true},
+		{`semver(.current) < "1.9.3"`, false},
+		{`semver("1.2.0-rc.1") < semver("1.2.0")`, true},
+		{`semver("v1.2") == semver("1.2.0")`, true},
+		{`.current > .next`, false},
+	}
+
+	for _, tt := range conditions {
+		t.Run(tt.condition, func(t *testing.T) {
+			got, err := evaluateCondition(tt.condition, ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestStrictTemplateMode tests undefined variable errors and suggestions in strict mode
+func TestStrictTemplateMode(t *testing.T) {
+	ctx := &ExecutionContext{
+		Vars: map[string]interface{}{
+			"project": "shef",
+			"build":   map[string]interface{}{"status": "ok"},
+		},
+		OperationOutputs: map[string]string{},
+		OperationResults: map[string]bool{},
+		operationIDs:     []string{"deploy_output"},
+	}
+
+	result, err := renderTemplate("echo {{ .projct }}", ctx.templateVars())
+	assert.NoError(t, err)
+	assert.Equal(t, "echo false", result)
+
+	ctx.Strict = true
+
+	result, err = renderTemplate("echo {{ .project }}", ctx.templateVars())
+	assert.NoError(t, err)
+	assert.Equal(t, "echo shef", result)
+
+	tests := []struct {
+		name        string
+		template    string
+		key         string
+		line        int
+		column      int
+		suggestions []string
+	}{
+		{"top level variable", "echo {{ .projct }}", "projct", 1, 8, []string{"project"}},
+		{"operation id", "cat <<EOF\n{{ .deploy_outptu }}\nEOF", "deploy_outptu", 2, 3, []string{"deploy_output"}},
+		{"nested field", "echo {{ .build.stauts }}", "stauts", 1, 14, []string{"status"}},
+		{"no close match", "echo {{ .zzz }}", "zzz", 1, 8, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderTemplate(tt.template, ctx.templateVars())
+
+			var undefinedErr *undefinedVariableError
+			if assert.ErrorAs(t, err, &undefinedErr) {
+				assert.Equal(t, tt.key, undefinedErr.Key)
+				assert.Equal(t, tt.line, undefinedErr.Line)
+				assert.Equal(t, tt.column, undefinedErr.Column)
+				assert.Equal(t, tt.suggestions, undefinedErr.Suggestions)
+			}
+		})
+	}
+
+	err = annotateUndefinedVariable(annotateUndefinedVariable(
+		&undefinedVariableError{Key: "projct", Line: 1, Column: 8}, "Inner"), "Outer")
+	assert.Contains(t, err.Error(), "operation 'Inner': undefined variable \"projct\" at line 1, column 9")
+	assert.NotContains(t, err.Error(), "Outer")
+}
+
+// TestTemplateVarsCache tests reusing, overlaying and invalidating the cached template variables
+func TestTemplateVarsCache(t *testing.T) {
+	ctx := &ExecutionContext{
+		Vars:             map[string]interface{}{"name": "first"},
+		OperationOutputs: map[string]string{},
+		OperationData:    map[string]interface{}{},
+		OperationResults: map[string]bool{},
+	}
+
+	vars := ctx.templateVars()
+	assert.Equal(t, reflect.ValueOf(vars).Pointer(), reflect.ValueOf(ctx.templateVars()).Pointer())
+
+	ctx.setVar("name", "second")
+	assert.Equal(t, "second", ctx.templateVars()["name"])
+	assert.Equal(t, "first", vars["name"])
+
+	ctx.OperationOutputs["build"] = "done"
+	ctx.invalidateTemplateVars()
+	assert.Equal(t, "done", ctx.templateVars()["build"])
+
+	ctx.setVar("build", "shadowed")
+	assert.Equal(t, "done", ctx.templateVars()["build"])
+
+	result, err := transformOutput("raw", "{{ .output }}", ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "raw", result)
+	assert.NotContains(t, ctx.templateVars(), "output")
+
+	ctx.deleteVar("name")
+	assert.NotContains(t, ctx.templateVars(), "name")
+
+	ctx.templates = templateCache{}
+	for i := 0; i < 3; i++ {
+		result, err = renderTemplate("{{ .build }}", ctx.templateVars())
+		assert.NoError(t, err)
+		assert.Equal(t, "done", result)
+	}
+	assert.Len(t, ctx.templates.templates, 1)
+
+	result, err = renderTemplate("no actions here", ctx.templateVars())
+	assert.NoError(t, err)
+	assert.Equal(t, "no actions here", result)
+	assert.Len(t, ctx.templates.templates, 1)
+
+	loop := ctx.pushLoopContext("foreach", 0)
+	loop.Duration = 1500 * time.Millisecond
+	assert.Equal(t, "1500", ctx.templateVars()["duration_ms"])
+	built := ctx.scope.builtVersion
+	loop.Duration = 2500 * time.Millisecond
+	assert.Equal(t, "2500", ctx.templateVars()["duration_ms"])
+	assert.Equal(t, "2", ctx.templateVars()["duration_s"])
+	assert.Equal(t, built, ctx.scope.builtVersion)
+
+	ctx.popLoopContext()
+	assert.NotContains(t, ctx.templateVars(), "duration_ms")
+}
+
+// newBenchmarkContext creates an execution context with a realistic number of variables and outputs
+func newBenchmarkContext() *ExecutionContext {
+	ctx := &ExecutionContext{
+		Vars:             make(map[string]interface{}),
+		OperationOutputs: make(map[string]string),
+		OperationData:    make(map[string]interface{}),
+		OperationResults: make(map[string]bool),
+	}
+	for i := 0; i < 200; i++ {
+		ctx.Vars[fmt.Sprintf("var_%d", i)] = fmt.Sprintf("value %d", i)
+		ctx.OperationOutputs[fmt.Sprintf("op_%d", i)] = strings.Repeat("output line\n", 10)
+	}
+	return ctx
+}
+
+// BenchmarkRenderTemplate measures rendering a command template against a large execution context
+func BenchmarkRenderTemplate(b *testing.B) {
+	ctx := newBenchmarkContext()
+	tmpl := `echo "{{ .var_1 }} {{ upper .var_2 }}" | grep {{ .op_3 | trim | count }}`
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := renderTemplate(tmpl, ctx.templateVars()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderTemplateNoActions measures rendering a command without template actions
+func BenchmarkRenderTemplateNoActions(b *testing.B) {
+	ctx := newBenchmarkContext()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := renderTemplate("echo hello world", ctx.templateVars()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkForEachIteration simulates the templates rendered for one foreach iteration: the loop duration and
+// variable change once, then the condition, command and transform are rendered against them
+func BenchmarkForEachIteration(b *testing.B) {
+	ctx := newBenchmarkContext()
+	ctx.pushLoopContext("foreach", 0)
+	defer ctx.popLoopContext()
+	templates := []string{
+		`{{ if eq .item "skip" }}false{{ else }}true{{ end }}`,
+		`echo "Processing {{ .item }} ({{ .iteration }})"`,
+		`{{ .item | upper }}`,
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx.updateLoopDuration()
+		ctx.setVar("item", fmt.Sprintf("item-%d", i))
+		ctx.setVar("iteration", i+1)
+		for _, tmpl := range templates {
+			if _, err := renderTemplate(tmpl, ctx.templateVars()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// TestTemplateExecFunctions tests the exec and execOrFail template functions and their options
+func TestTemplateExecFunctions(t *testing.T) {
+	workdir := t.TempDir()
+	ctx := &ExecutionContext{
+		Vars:             map[string]interface{}{"workdir": workdir},
+		OperationOutputs: map[string]string{},
+		OperationResults: map[string]bool{},
+	}
+
+	result, err := renderTemplate(`{{ exec "pwd" | trim | base }}`, ctx.templateVars())
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Base(workdir), result)
+
+	result, err = renderTemplate(`[{{ exec "exit 1" }}]`, ctx.templateVars())
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", result)
+
+	previous := ctx.setExecOptions(&execOptions{Operation: "Deploy", Timeout: 100 * time.Millisecond})
+	_, err = renderTemplate(`{{ execOrFail "sleep 2" }}`, ctx.templateVars())
+	var execErr *templateExecError
+	if assert.ErrorAs(t, err, &execErr) {
+		assert.Equal(t, "Deploy", execErr.Operation)
+		assert.EqualError(t, execErr.Err, "timed out after 100ms")
+	}
+	ctx.setExecOptions(previous)
+
+	_, err = renderTemplate(`{{ execOrFail "echo broken >&2; exit 2" }}`, ctx.templateVars())
+	assert.EqualError(t, err, `exec "echo broken >&2; exit 2" failed: exit status 2: broken`)
+
+	ctx.DryRun = true
+	result, err = renderTemplate(`[{{ execOrFail "touch should-not-exist" }}]`, ctx.templateVars())
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", result)
+	assert.NoFileExists(t, filepath.Join(workdir, "should-not-exist"))
+}
+
+// TestPromptValidators tests the declarative prompt validation rules
+func TestPromptValidators(t *testing.T) {
+	validate := func(rules []PromptValidator, value interface{}) error {
+		validator, err := promptValidator(Prompt{Name: "answer", Validators: rules}, nil)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		return validator(value)
+	}
+
+	assert.NoError(t, validate([]PromptValidator{{Type: "hostname"}}, "api.example.com"))
+	assert.EqualError(t, validate([]PromptValidator{{Type: "hostname"}}, "-bad-.example.com"), "please enter a valid hostname")
+	assert.NoError(t, validate([]PromptValidator{{Type: "url"}}, "https://example.com/path"))
+	assert.EqualError(t, validate([]PromptValidator{{Type: "url"}}, "example.com"), "please enter a valid URL")
+	assert.EqualError(t, validate([]PromptValidator{{Type: "email", Message: "Use your work email"}}, "Dev <dev@example.com>"), "Use your work email")
+	assert.EqualError(t, validate([]PromptValidator{{Type: "length", Min: 3}}, "añ"), "value must be at least 3 characters")
+	assert.NoError(t, validate([]PromptValidator{{Type: "email"}}, ""), "empty values are left to the required validator")
+	assert.EqualError(t, validate([]PromptValidator{{Type: "required"}}, "  "), "value is required")
+
+	selected := []core.OptionAnswer{{Value: "api"}, {Value: "web"}}
+	assert.EqualError(t, validate([]PromptValidator{{Type: "length", Max: 1}}, selected), "select at most 1 options")
+	assert.EqualError(t, validate([]PromptValidator{{Type: "one_of", Options: []string{"api"}}}, selected), "value must be one of: api")
+	assert.EqualError(t, validate([]PromptValidator{{Type: "required"}}, []core.OptionAnswer{}), "select at least one option")
+	assert.NoError(t, validate([]PromptValidator{{Type: "one_of", Options: []string{"dev"}}}, core.OptionAnswer{Value: ExitPrompt}))
+
+	assert.NoError(t, validate([]PromptValidator{{Type: "command", Command: `grep -q '^v[0-9]'`}}, "v1.2.0"))
+	assert.EqualError(t, validate([]PromptValidator{{Type: "command", Command: `echo "bad tag $1"; exit 1`}}, "1.2.0"), "bad tag 1.2.0")
+
+	_, err := promptValidator(Prompt{Name: "answer", Validators: []PromptValidator{{Type: "palindrome"}}}, nil)
+	assert.EqualError(t, err, "prompt 'answer': unknown validator type: palindrome")
+	_, err = promptValidator(Prompt{Name: "answer", Validators: []PromptValidator{{Type: "regex", Pattern: "("}}}, nil)
+	assert.ErrorContains(t, err, "invalid validator pattern")
+}
+
+// TestPromptMemory tests remembering prompt answers between runs
+func TestPromptMemory(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	recipe := Recipe{Name: "deploy", Category: "ops", RememberPrompts: true}
+	never := false
+
+	memory := newPromptMemory(recipe)
+	memory.remember(Prompt{Name: "services", Type: "multiselect"}, []string{"api", "web"})
+	memory.remember(Prompt{Name: "Password", ID: "password", Type: "password"}, "hunter2")
+	memory.remember(Prompt{Name: "note", Type: "input", Remember: &never}, "hello")
+
+	memory = newPromptMemory(recipe)
+	answer, ok := memory.recall(Prompt{Name: "services", Type: "multiselect"})
+	assert.True(t, ok)
+	assert.Equal(t, "api,web", answer)
+	_, ok = memory.recall(Prompt{Name: "Password", ID: "password", Type: "password"})
+	assert.False(t, ok)
+	_, ok = memory.recall(Prompt{Name: "note", Type: "input"})
+	assert.False(t, ok)
+
+	_, ok = newPromptMemory(Recipe{Name: "deploy", Category: "ops"}).recall(Prompt{Name: "services", Type: "multiselect"})
+	assert.False(t, ok, "answers are only offered when remembering is enabled")
+
+	assert.NoError(t, forgetPromptAnswers(recipe))
+	_, ok = newPromptMemory(recipe).recall(Prompt{Name: "services", Type: "multiselect"})
+	assert.False(t, ok)
+	assert.NoError(t, forgetPromptAnswers(recipe), "forgetting twice is not an error")
+}
+
+// TestFuzzySelect tests ranking and picking options in the fuzzy finder prompt
+func TestFuzzySelect(t *testing.T) {
+	options := []string{"nginx:latest", "ghcr.io/acme/nginx-proxy", "ngx_tools", "redis", ExitPrompt}
+	descriptions := map[string]string{"redis": "in-memory cache"}
+
+	var ranked []string
+	for _, result := range rankFuzzyOptions("ngx", options, descriptions) {
+		ranked = append(ranked, result.Value)
+	}
+	assert.Equal(t, []string{"ngx_tools", "nginx:latest", "ghcr.io/acme/nginx-proxy"}, ranked)
+
+	results := rankFuzzyOptions("mem cache", options, descriptions)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "redis", results[0].Value)
+		assert.Equal(t, []fuzzySegment{{Text: "in-", Match: false}, {Text: "mem", Match: true}, {Text: "ory ", Match: false}, {Text: "cache", Match: true}},
+			fuzzySegments(results[0].Description, results[0].DescriptionPositions))
+	}
+
+	_, _, ok := fuzzyMatch("Nginx", "nginx:latest")
+	assert.False(t, ok, "upper case terms match case-sensitively")
+	assert.Len(t, rankFuzzyOptions("", options, descriptions), len(options))
+
+	prompt := &FuzzySelect{Options: options, Descriptions: descriptions, Default: []string{"redis"}}
+	prompt.reset()
+	assert.True(t, prompt.handleKey(terminal.KeyEnter, "?"))
+	assert.Equal(t, core.OptionAnswer{Value: "redis", Index: 3}, prompt.answer())
+
+	prompt = &FuzzySelect{Options: options, Multi: true, Limit: 2}
+	prompt.reset()
+	for _, key := range "nginx" {
+		prompt.handleKey(key, "?")
+	}
+	prompt.handleKey(terminal.KeyTab, "?")
+	prompt.handleKey(terminal.KeyTab, "?")
+	prompt.handleKey(terminal.KeyDeleteLine, "?")
+	prompt.handleKey(terminal.KeyArrowUp, "?")
+	prompt.handleKey(terminal.KeyTab, "?")
+	assert.True(t, prompt.handleKey(terminal.KeyEnter, "?"))
+	assert.Equal(t, []core.OptionAnswer{{Value: "nginx:latest", Index: 0}, {Value: "ghcr.io/acme/nginx-proxy", Index: 1}}, prompt.answer(),
+		"the selection limit stops a third option from being marked")
+}
+
+// TestTableSelect tests reading, filtering and picking rows in the table select prompt
+func TestTableSelect(t *testing.T) {
+	assert.Equal(t, []string{"name", "id", "tags"}, jsonObjectKeys([]byte(`{"name": "web", "id": 7, "tags": {"a": 1}}`)))
+	assert.Equal(t, "1000000", formatTableCell(float64(1000000)))
+	assert.Equal(t, `{"a":1}`, formatTableCell(map[string]interface{}{"a": float64(1)}))
+
+	ctx := &ExecutionContext{
+		Vars:             map[string]interface{}{},
+		OperationOutputs: map[string]string{"vms": `[{"id": "i-1", "name": "web"}, {"id": "i-2", "name": "db", "zone": "eu"}]`},
+		OperationResults: map[string]bool{},
+	}
+	rows, columns, err := getTableRows(Prompt{Name: "vm", SourceOp: "vms"}, ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "zone"}, columns)
+
+	prompt := &TableSelect{Columns: columns, Rows: rows, Keys: []string{"i-1", "i-2"}, Style: tableStyleByName("ascii")}
+	prompt.refresh()
+	assert.Len(t, prompt.rowLines, 2)
+	for _, key := range "db" {
+		prompt.handleKey(key, "?")
+	}
+	assert.Len(t, prompt.rowLines, 1)
+	assert.Contains(t, prompt.rowLines[0], "i-2")
+	assert.True(t, prompt.handleKey(terminal.KeyEnter, "?"))
+	assert.Equal(t, core.OptionAnswer{Value: "i-2", Index: 1}, prompt.answer())
+
+	prompt.handleKey(terminal.KeyArrowDown, "?")
+	assert.Equal(t, ExitPrompt, prompt.answer().Value)
+
+	_, _, err = getTableRows(Prompt{Name: "vm", SourceOp: "vms", SourceTransform: `{{ "not json" }}`}, ctx)
+	assert.ErrorContains(t, err, "is not a JSON array")
+
+	ctx.OperationOutputs["vms"] = `[{"id": 1234567890123, "cpu": 0.5}]`
+	picked, err := tableSelectAnswerFromFlag(Prompt{Name: "vm", SourceOp: "vms"}, "1234567890123", ctx)
+	assert.NoError(t, err)
+	result, err := renderTemplate("{{ .picked.id }} {{ .picked.cpu }}", map[string]interface{}{"picked": picked})
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890123 0.5", result)
+}
+
+// TestFormChecks tests form checks and formatting answers for the review screen
+func TestFormChecks(t *testing.T) {
+	ctx := &ExecutionContext{
+		Vars:             map[string]interface{}{},
+		OperationOutputs: map[string]string{},
+		OperationResults: map[string]bool{},
+	}
+	form := Prompt{Name: "secret", Type: "form", Checks: []FormCheck{
+		{Condition: `.project != "prod" || .replicas >= 2`, Message: "prod needs 2 replicas"},
+		{Condition: `.replicas <= 5`},
+	}}
+
+	ctx.storePromptAnswer("project", "prod")
+	ctx.storePromptAnswer("replicas", 1)
+	assert.Equal(t, []string{"prod needs 2 replicas"}, failedFormChecks(form, ctx))
+
+	ctx.storePromptAnswer("replicas", 6)
+	assert.Equal(t, []string{"check failed: .replicas <= 5"}, failedFormChecks(form, ctx))
+
+	ctx.storePromptAnswer("replicas", 3)
+	assert.Empty(t, failedFormChecks(form, ctx))
+
+	assert.Equal(t, "********", formatFormValue(Prompt{Type: "password"}, "hunter2"))
+	assert.Equal(t, "********", formatFormValue(Prompt{Type: "input", Secret: true}, "token"))
+	assert.Equal(t, "api, web", formatFormValue(Prompt{Type: "multiselect"}, []string{"api", "web"}))
+	assert.Equal(t, "api,web", formatPromptDefault([]string{"api", "web"}))
+}
+
+// TestTypedPromptAnswers tests parsing date, duration, list and key-value answers
+func TestTypedPromptAnswers(t *testing.T) {
+	assert.Equal(t, "02/01/2006 15:04", strftimeLayout("%d/%m/%Y %H:%M"))
+	assert.Equal(t, "2006-01-02", strftimeLayout("2006-01-02"))
+
+	date, err := parseDateAnswer(Prompt{Type: "date", MinDate: "2025-01-01"}, "2025-06-30")
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-06-30", date.String())
+	assert.Equal(t, "June", date.Month().String())
+
+	_, err = parseDateAnswer(Prompt{Type: "date", MaxDate: "2025-01-01"}, "2025-06-30")
+	assert.EqualError(t, err, "date must be on or before 2025-01-01")
+
+	today := time.Now().Format("2006-01-02")
+	date, err = parseDateAnswer(Prompt{Type: "date", MinDate: "today"}, "today")
+	assert.NoError(t, err)
+	assert.Equal(t, today, date.String())
+	date, err = parseDateAnswer(Prompt{Type: "date"}, "+1d")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().AddDate(0, 0, 1).Format("2006-01-02"), date.String())
+
+	datetime, err := parseDateAnswer(Prompt{Type: "datetime", Format: "%Y-%m-%dT%H:%M"}, "2025-06-30T08:15")
+	assert.NoError(t, err)
+	data, err := json.Marshal(datetime)
+	assert.NoError(t, err)
+	assert.Equal(t, `"2025-06-30T08:15"`, string(data))
+
+	d, err := parseDurationAnswer("1h30m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+	d, err = parseDurationAnswer("2d")
+	assert.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, d)
+
+	assert.Equal(t, []string{"a", "b,c"}, parseListAnswer("a\n\n b,c \n"))
+	assert.Equal(t, []string{"a", "b"}, parseListAnswer("a, b,"))
+	assert.Equal(t, []string{}, parseListAnswer(""))
+
+	pairs, err := parseKeyValueAnswer("team=payments\nquery=a=b\nteam=billing")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"team": "billing", "query": "a=b"}, pairs)
+	assert.Equal(t, "query=a=b,team=billing", formatPromptDefault(pairs))
+	_, err = parseKeyValueAnswer("=value")
+	assert.EqualError(t, err, `expected key=value, got "=value"`)
+}
+
+// TestRecipeIndex tests building, caching and invalidating the recipe index
+func TestRecipeIndex(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	recipePath := filepath.Join(".shef", "ops.yaml")
+	assert.NoError(t, os.MkdirAll(".shef", 0755))
+	assert.NoError(t, os.WriteFile(recipePath, []byte(`recipes:
+  - name: deploy
+    category: ops
+    operations:
+      - name: Deploy
+        command: echo deploy
+components:
+  - id: greet
+    operations:
+      - name: Greet
+        command: echo hi
+`), 0644))
+
+	catalog := buildRecipeCatalog()
+	recipes := catalog.sourceRecipes("local", "ops")
+	if assert.Len(t, recipes, 1) {
+		assert.Equal(t, recipePath, recipes[0].Path)
+		recipe, err := recipes[0].load()
+		assert.NoError(t, err)
+		assert.Len(t, recipe.Operations, 1)
+	}
+	assert.Equal(t, []string{recipePath}, catalog.componentFiles(recipeSources))
+
+	index := loadRecipeIndex()
+	absPath, _ := filepath.Abs(recipePath)
+	entry, ok := index.Files[absPath]
+	assert.True(t, ok, "the index is written to the cache directory")
+
+	entry.Recipes[0].Name = "cached"
+	index.Files[absPath] = entry
+	assert.NoError(t, index.save())
+	assert.Equal(t, "cached", buildRecipeCatalog().sourceRecipes("local", "")[0].Name, "unchanged files are not parsed again")
+
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(recipePath, later, later))
+	assert.Equal(t, "deploy", buildRecipeCatalog().sourceRecipes("local", "")[0].Name, "changed files are indexed again")
+
+	assert.NoError(t, os.Remove(recipePath))
+	assert.Empty(t, buildRecipeCatalog().sourceRecipes("local", ""))
+	assert.Empty(t, loadRecipeIndex().Files, "removed files are dropped from the index")
+}
+
+// TestStructuredOutputNumbers tests that numbers in JSON output compare like numbers in YAML output
+func TestStructuredOutputNumbers(t *testing.T) {
+	for _, format := range []string{OutputFormatJSON, OutputFormatYAML} {
+		output := `{"count": 3, "ratio": 0.5}`
+		if format == OutputFormatYAML {
+			output = "count: 3\nratio: 0.5"
+		}
+
+		value, err := parseStructuredOutput(output, format)
+		assert.NoError(t, err)
+
+		result, err := renderTemplate(`{{ eq .data.count 3 }} {{ gt .data.count 2 }} {{ lt .data.ratio 1.0 }}`,
+			map[string]interface{}{"data": value})
+		assert.NoError(t, err, format)
+		assert.Equal(t, "true true true", result, format)
+	}
+}
+
+// TestPruneRunDirectories tests that pruning keeps live runs and ranks runs by their newest file
+func TestPruneRunDirectories(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+
+	makeRun := func(name string, fileTime time.Time) string {
+		dir := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "tasks"), 0755))
+		logPath := filepath.Join(dir, "tasks", "task.log")
+		assert.NoError(t, os.WriteFile(logPath, []byte("output"), 0644))
+		assert.NoError(t, os.Chtimes(logPath, fileTime, fileTime))
+		for _, path := range []string{filepath.Join(dir, "tasks"), dir} {
+			assert.NoError(t, os.Chtimes(path, old, old))
+		}
+		return dir
+	}
+
+	live := makeRun("live", old.Add(-time.Hour))
+	assert.NoError(t, os.WriteFile(filepath.Join(live, runPIDFile), []byte(fmt.Sprint(os.Getpid())), 0644))
+	assert.NoError(t, os.Chtimes(filepath.Join(live, runPIDFile), old, old))
+	streaming := makeRun("streaming", time.Now())
+	finished := makeRun("finished", old.Add(time.Minute))
+
+	pruneRunDirectories(root, 1)
+
+	assert.DirExists(t, live, "live runs are never pruned")
+	assert.DirExists(t, streaming, "runs with recently written logs are kept")
+	assert.NoDirExists(t, finished)
+}
+
+// TestCompletionValues tests completing prompt values and filtering completion candidates
+func TestCompletionValues(t *testing.T) {
+	p := Prompt{
+		Name:         "services",
+		Type:         "multiselect",
+		Options:      []string{"api", "web", "{{ .dynamic }}"},
+		Descriptions: map[string]string{"web": "Web frontend\nserved by nginx"},
+	}
+
+	values := promptValueCompletions(p, "--services=", "api,w")
+	assert.Equal(t, []completion{{Value: "--services=api,web", Description: "Web frontend"}}, values)
+
+	confirm := promptValueCompletions(Prompt{Type: "confirm"}, "--ok=", "")
+	assert.Equal(t, []completion{{Value: "--ok=true"}, {Value: "--ok=false"}}, confirm)
+
+	assert.Nil(t, promptValueCompletions(Prompt{Type: "input"}, "--name=", ""))
+
+	candidates := []completion{{Value: "logs", Description: "command"}, {Value: "list"}, {Value: "logs", Description: "recipe"}}
+	assert.Equal(t, []completion{{Value: "logs", Description: "command"}}, filterCompletions(candidates, "lo"))
+}
+
+// TestSearchRecipes tests ranking recipes by matches across their fields
+func TestSearchRecipes(t *testing.T) {
+	recipes := []recipeSummary{
+		{Name: "backup-database", Description: "Dump the database to a bucket", Category: "db"},
+		{Name: "restore", Description: "Restore a backup", Category: "db", Commands: []string{"pg_restore dump.sql"}},
+		{Name: "lint", Description: "Run linters", Help: "Checks the code base", Author: "tools team"},
+	}
+
+	names := func(results []searchResult) []string {
+		var n []string
+		for _, r := range results {
+			n = append(n, r.Recipe.Name)
+		}
+		return n
+	}
+
+	results := searchRecipes(recipes, "backup")
+	assert.Equal(t, []string{"backup-database", "restore"}, names(results))
+	assert.Equal(t, []string{"name"}, results[0].Matches)
+	assert.Equal(t, []string{"description"}, results[1].Matches)
+
+	assert.Equal(t, []string{"restore"}, names(searchRecipes(recipes, "db pg_restore")))
+	assert.Equal(t, []string{"lint"}, names(searchRecipes(recipes, "tools code")))
+	assert.Equal(t, []string{"restore"}, names(searchRecipes(recipes, "restroe")))
+	assert.Empty(t, searchRecipes(recipes, "xyz"))
+	assert.Len(t, searchRecipes(recipes, ""), 3)
+}
+
 // TestShefEndToEnd runs all end-to-end tests within ./testdata
 func TestShefEndToEnd(t *testing.T) {
 	testscript.Run(t, testscript.Params{
@@ -2,10 +2,14 @@ package internal
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -28,6 +32,67 @@ func executeCommand(cmdStr string, input string, executionMode string, outputFor
 	}
 }
 
+// resolveStdin determines what an operation's command receives on stdin
+func resolveStdin(op Operation, ctx *ExecutionContext, workdir string) (string, error) {
+	switch stdin := op.Stdin.(type) {
+	case nil:
+		if ctx.DefaultStdin == StdinNone {
+			return "", nil
+		}
+		return ctx.Data, nil
+
+	case string:
+		switch stdin {
+		case StdinNone:
+			return "", nil
+		case StdinPrevious:
+			return ctx.Data, nil
+		}
+		rendered, err := renderTemplate(stdin, ctx.templateVars())
+		if err != nil {
+			return "", fmt.Errorf("failed to render stdin template: %w", err)
+		}
+		return rendered, nil
+
+	case map[string]interface{}:
+		if from, ok := stdin["from"]; ok {
+			opID, err := renderTemplate(fmt.Sprintf("%v", from), ctx.templateVars())
+			if err != nil {
+				return "", fmt.Errorf("failed to render stdin source template: %w", err)
+			}
+
+			ctx.OperationMutex.RLock()
+			output, exists := ctx.OperationOutputs[opID]
+			ctx.OperationMutex.RUnlock()
+			if !exists {
+				return "", fmt.Errorf("stdin source operation %s not found or has no output", opID)
+			}
+			return output, nil
+		}
+
+		if file, ok := stdin["file"]; ok {
+			path, err := renderTemplate(fmt.Sprintf("%v", file), ctx.templateVars())
+			if err != nil {
+				return "", fmt.Errorf("failed to render stdin file template: %w", err)
+			}
+			if !filepath.IsAbs(path) && workdir != "" {
+				path = filepath.Join(workdir, path)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read stdin file: %w", err)
+			}
+			return string(data), nil
+		}
+
+		return "", fmt.Errorf("stdin requires a 'from' or 'file' field")
+
+	default:
+		return "", fmt.Errorf("invalid stdin value: %v", op.Stdin)
+	}
+}
+
 // escapeShellString properly escapes a string for shell execution
 func escapeShellString(s string) string {
 	s = strings.ReplaceAll(s, "\\", "\\\\")
@@ -55,8 +120,22 @@ func prepShellCmd(cmdStr string, useUserShell bool, rawCommand bool) string {
 
 // executeStandardCommand runs a command and captures its output
 func executeStandardCommand(cmdStr string, input string, outputFormat string, workdir string, useUserShell bool, rawCommand bool) (string, error) {
+	return executeStandardCommandContext(context.Background(), cmdStr, input, outputFormat, workdir, useUserShell, rawCommand, nil)
+}
+
+// executeStandardCommandContext runs a command that is killed when the given context is done,
+// optionally streaming its stdout and stderr to logWriter as they are produced
+func executeStandardCommandContext(cmdCtx context.Context, cmdStr string, input string, outputFormat string, workdir string, useUserShell bool, rawCommand bool, logWriter io.Writer) (string, error) {
 	command := prepShellCmd(cmdStr, useUserShell, rawCommand)
-	cmd := exec.Command(ExecShell, "-c", command)
+	cmd := exec.CommandContext(cmdCtx, ExecShell, "-c", command)
+	cmd.WaitDelay = time.Second
+	if cmdCtx.Done() != nil {
+		// a command that can be cancelled runs in its own process group, so the processes it starts are stopped too
+		cmd.SysProcAttr = processGroupProcAttr()
+		cmd.Cancel = func() error {
+			return killProcessGroup(cmd.Process.Pid)
+		}
+	}
 
 	if workdir != "" {
 		cmd.Dir = workdir
@@ -69,6 +148,10 @@ func executeStandardCommand(cmdStr string, input string, outputFormat string, wo
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if logWriter != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, logWriter)
+		cmd.Stderr = io.MultiWriter(&stderr, logWriter)
+	}
 
 	err := cmd.Run()
 	if err != nil {
@@ -155,7 +238,7 @@ func interruptAndWaitForCommand(cmd *exec.Cmd, done chan error) error {
 }
 
 // executeBackgroundCommand runs a command asynchronously in the background
-func executeBackgroundCommand(op Operation, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), depth int, workdir string) error {
+func executeBackgroundCommand(op Operation, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), depth int, workdir string, input string) error {
 	if op.ID == "" {
 		return fmt.Errorf("background execution requires an operation ID")
 	}
@@ -183,6 +266,16 @@ func executeBackgroundCommand(op Operation, ctx *ExecutionContext, opMap map[str
 		Log(CategoryTemplate, fmt.Sprintf("Using raw command for background task '%s' (bypassing template rendering)", op.ID))
 	}
 
+	timeout, err := parseTaskTimeout(op.Timeout, ctx)
+	if err != nil {
+		return err
+	}
+
+	runDir, err := ctx.ensureRunDir()
+	if err != nil {
+		return err
+	}
+
 	ctx.BackgroundMutex.Lock()
 	task, exists := ctx.BackgroundTasks[op.ID]
 	if exists && task.Status == TaskPending {
@@ -191,19 +284,47 @@ func executeBackgroundCommand(op Operation, ctx *ExecutionContext, opMap map[str
 		return nil
 	}
 
-	LogBackgroundTask(op.ID, "starting", map[string]interface{}{"command": cmd})
+	LogBackgroundTask(op.ID, "starting", map[string]interface{}{"command": cmd, "timeout": timeout.String()})
 
-	initializeBackgroundTask(op.ID, cmd, ctx)
+	task = initializeBackgroundTask(op.ID, cmd, ctx)
+	task.LogPath = filepath.Join(runDir, "tasks", sanitizeFileName(op.ID)+".log")
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		taskCtx, cancel = context.WithCancel(context.Background())
+	}
+	task.cancel = cancel
 	ctx.BackgroundMutex.Unlock()
 
 	ctx.BackgroundWg.Add(1)
-	go executeBackgroundTask(op, cmd, ctx, opMap, executeOp, depth, workdir)
+	go executeBackgroundTask(taskCtx, op, task, cmd, input, ctx, opMap, executeOp, depth, workdir, timeout)
 
 	return nil
 }
 
+// parseTaskTimeout renders and parses a background task or exec timeout such as "30s" or "5m"
+func parseTaskTimeout(timeout string, ctx *ExecutionContext) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+
+	rendered, err := renderTemplate(timeout, ctx.templateVars())
+	if err != nil {
+		return 0, fmt.Errorf("failed to render timeout template: %w", err)
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(rendered))
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout value: %s", rendered)
+	}
+
+	return duration, nil
+}
+
 // initializeBackgroundTask sets up a new background task in the context
-func initializeBackgroundTask(taskID, cmd string, ctx *ExecutionContext) {
+func initializeBackgroundTask(taskID, cmd string, ctx *ExecutionContext) *BackgroundTask {
 	if ctx.BackgroundTasks == nil {
 		ctx.BackgroundTasks = make(map[string]*BackgroundTask)
 	}
@@ -212,43 +333,233 @@ func initializeBackgroundTask(taskID, cmd string, ctx *ExecutionContext) {
 		ID:      taskID,
 		Command: cmd,
 		Status:  TaskPending,
+		done:    make(chan struct{}),
 	}
 
 	ctx.BackgroundTasks[taskID] = task
 	ctx.OperationMutex.Lock()
 	ctx.OperationOutputs[taskID] = string(TaskPending)
 	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
 	ctx.OperationResults[taskID] = false
+
+	return task
 }
 
 // executeBackgroundTask runs the task in a goroutine and handles success/failure
-func executeBackgroundTask(op Operation, cmd string, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), depth int, workdir string) {
+func executeBackgroundTask(taskCtx context.Context, op Operation, task *BackgroundTask, cmd string, input string, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), depth int, workdir string, timeout time.Duration) {
 	defer ctx.BackgroundWg.Done()
-
-	output, err := executeStandardCommand(cmd, ctx.Data, op.OutputFormat, workdir, op.UserShell, op.RawCommand)
+	defer close(task.done)
+	defer task.cancel()
+
+	var output string
+	logFile, err := os.Create(task.LogPath)
+	if err == nil {
+		if acquireBackgroundSlot(taskCtx, ctx) {
+			output, err = executeStandardCommandContext(taskCtx, cmd, input, op.OutputFormat, workdir, op.UserShell, op.RawCommand, logFile)
+			releaseBackgroundSlot(ctx)
+		} else {
+			err = taskCtx.Err()
+		}
+		safeClose(logFile, "background task log")
+	} else {
+		err = fmt.Errorf("failed to create log file for background task: %w", err)
+	}
 
 	ctx.BackgroundMutex.Lock()
 	defer ctx.BackgroundMutex.Unlock()
 
-	taskID := op.ID
-	task := ctx.BackgroundTasks[taskID]
-
-	if err != nil {
+	switch {
+	case errors.Is(taskCtx.Err(), context.DeadlineExceeded) && err != nil:
+		handleBackgroundTaskFailure(op, task, ctx, opMap, executeOp, fmt.Errorf("timed out after %s", timeout), depth)
+	case errors.Is(taskCtx.Err(), context.Canceled) && err != nil:
+		handleBackgroundTaskCancelled(op, task, ctx)
+	case err != nil:
 		handleBackgroundTaskFailure(op, task, ctx, opMap, executeOp, err, depth)
-	} else {
+	default:
 		handleBackgroundTaskSuccess(op, task, ctx, opMap, executeOp, output, depth)
 	}
 }
 
+// acquireBackgroundSlot blocks until the max_background pool has room, returning false if the task is cancelled first
+func acquireBackgroundSlot(taskCtx context.Context, ctx *ExecutionContext) bool {
+	if ctx.BackgroundSlots == nil {
+		return true
+	}
+
+	select {
+	case ctx.BackgroundSlots <- struct{}{}:
+		return true
+	case <-taskCtx.Done():
+		return false
+	}
+}
+
+// releaseBackgroundSlot frees a slot in the max_background pool
+func releaseBackgroundSlot(ctx *ExecutionContext) {
+	if ctx.BackgroundSlots != nil {
+		<-ctx.BackgroundSlots
+	}
+}
+
+// handleBackgroundTaskCancelled processes a background task that was cancelled
+func handleBackgroundTaskCancelled(op Operation, task *BackgroundTask, ctx *ExecutionContext) {
+	task.Status = TaskCancelled
+	task.Error = "cancelled"
+	ctx.OperationResults[op.ID] = false
+	ctx.OperationMutex.Lock()
+	ctx.OperationOutputs[op.ID] = string(TaskCancelled)
+	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
+
+	LogBackgroundTask(op.ID, "cancelled", nil)
+}
+
+// waitForBackgroundTasks blocks until each background task listed in wait_for has finished
+func waitForBackgroundTasks(op Operation, ctx *ExecutionContext) error {
+	for _, id := range op.WaitFor {
+		taskID, err := renderTemplate(id, ctx.templateVars())
+		if err != nil {
+			return fmt.Errorf("failed to render wait_for template: %w", err)
+		}
+
+		ctx.BackgroundMutex.RLock()
+		task, exists := ctx.BackgroundTasks[taskID]
+		ctx.BackgroundMutex.RUnlock()
+		if !exists {
+			return fmt.Errorf("cannot wait for unknown background task: %s", taskID)
+		}
+
+		LogBackgroundTask(taskID, "waiting", map[string]interface{}{"operation": op.Name})
+		<-task.done
+	}
+
+	return nil
+}
+
+// cancelBackgroundTask stops the background task named by cancel and waits for it to exit
+func cancelBackgroundTask(op Operation, ctx *ExecutionContext) error {
+	taskID, err := renderTemplate(op.Cancel, ctx.templateVars())
+	if err != nil {
+		return fmt.Errorf("failed to render cancel template: %w", err)
+	}
+
+	ctx.BackgroundMutex.RLock()
+	task, exists := ctx.BackgroundTasks[taskID]
+	ctx.BackgroundMutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("cannot cancel unknown background task: %s", taskID)
+	}
+
+	LogBackgroundTask(taskID, "cancelling", map[string]interface{}{"operation": op.Name})
+	task.cancel()
+	<-task.done
+
+	return nil
+}
+
+// attachToBackgroundTask streams a background task's log to stdout until the task finishes
+func attachToBackgroundTask(op Operation, ctx *ExecutionContext) error {
+	taskID, err := renderTemplate(op.Attach, ctx.templateVars())
+	if err != nil {
+		return fmt.Errorf("failed to render attach template: %w", err)
+	}
+
+	ctx.BackgroundMutex.RLock()
+	task, exists := ctx.BackgroundTasks[taskID]
+	ctx.BackgroundMutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("cannot attach to unknown background task: %s", taskID)
+	}
+
+	LogBackgroundTask(taskID, "attaching", map[string]interface{}{"operation": op.Name, "log": task.LogPath})
+	return followLogFile(task.LogPath, task.done, os.Stdout)
+}
+
+// followLogFile copies a growing log file to the writer until done is closed
+func followLogFile(path string, done <-chan struct{}, w io.Writer) error {
+	var file *os.File
+	defer func() {
+		if file != nil {
+			safeClose(file, "log file")
+		}
+	}()
+
+	for {
+		finished := false
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+
+		if file == nil {
+			if f, err := os.Open(path); err == nil {
+				file = f
+			} else if finished {
+				return nil
+			}
+		}
+
+		if file != nil {
+			if _, err := io.Copy(w, file); err != nil {
+				return fmt.Errorf("failed to read log file %s: %w", path, err)
+			}
+		}
+
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-done:
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// tailLogFile returns the last n lines of a log file
+func tailLogFile(path string, n int) string {
+	data, err := os.ReadFile(path)
+	if err != nil || n <= 0 {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// backgroundFailure returns the error of the first failed background task marked with fail_recipe
+func (ctx *ExecutionContext) backgroundFailure() error {
+	ctx.BackgroundMutex.RLock()
+	defer ctx.BackgroundMutex.RUnlock()
+	return ctx.BackgroundError
+}
+
 // handleBackgroundTaskFailure processes a failed background task
 func handleBackgroundTaskFailure(op Operation, task *BackgroundTask, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), err error, depth int) {
 	task.Status = TaskFailed
 	task.Error = err.Error()
 	ctx.OperationResults[op.ID] = false
-	ctx.Vars["error"] = err.Error()
+	ctx.setVar("error", err.Error())
 	ctx.OperationMutex.Lock()
 	ctx.OperationOutputs[op.ID] = fmt.Sprintf("Error: %s", err.Error())
 	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
 
 	if op.ComponentInstanceID != "" {
 		ctx.ExecutedOperationsByComponent[op.ComponentInstanceID] =
@@ -257,6 +568,10 @@ func handleBackgroundTaskFailure(op Operation, task *BackgroundTask, ctx *Execut
 
 	LogBackgroundTask(op.ID, "failed", map[string]interface{}{"error": err.Error()})
 
+	if op.FailRecipe && ctx.BackgroundError == nil {
+		ctx.BackgroundError = fmt.Errorf("background task %s failed: %w", op.ID, err)
+	}
+
 	if op.OnFailure != "" {
 		executeFailureHandler(op, opMap, executeOp, depth)
 	}
@@ -278,8 +593,13 @@ func handleBackgroundTaskSuccess(op Operation, task *BackgroundTask, ctx *Execut
 	ctx.OperationMutex.Lock()
 	ctx.OperationOutputs[op.ID] = strings.TrimSpace(output)
 	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
 	ctx.OperationResults[op.ID] = true
 
+	if err := storeStructuredOutput(op, output, ctx); err != nil {
+		LogError(fmt.Sprintf("Failed to parse output of background task %s", op.ID), err, nil)
+	}
+
 	if op.ComponentInstanceID != "" {
 		ctx.ExecutedOperationsByComponent[op.ComponentInstanceID] =
 			append(ctx.ExecutedOperationsByComponent[op.ComponentInstanceID], op.ID)
@@ -0,0 +1,482 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// completion is a shell completion candidate with an optional description
+type completion struct {
+	Value       string
+	Description string
+}
+
+// completionScripts are the scripts printed by shef completion. Each script passes the words before the cursor and
+// the word being completed to shef __complete, which prints one candidate per line as value<TAB>description.
+var completionScripts = map[string]string{
+	"bash": bashCompletionScript,
+	"zsh":  zshCompletionScript,
+	"fish": fishCompletionScript,
+}
+
+const bashCompletionScript = `# bash completion for shef
+# Add to ~/.bashrc:  source <(shef completion bash)
+
+_shef_completions() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    if [[ -z "$line" || "$line" == *[[:space:]] ]]; then
+        words+=("")
+    fi
+
+    # bash splits words on = and :, so only the part after the last one is replaced
+    local word="${words[${#words[@]}-1]}"
+    local prefix="${word%"${word##*[=:]}"}"
+
+    COMPREPLY=()
+    local candidate
+    while IFS= read -r candidate; do
+        candidate="${candidate%%$'\t'*}"
+        COMPREPLY+=("${candidate#"$prefix"}")
+    done < <(shef __complete "${words[@]:1}" 2>/dev/null)
+
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == *= ]]; then
+        type compopt &>/dev/null && compopt -o nospace 2>/dev/null
+    fi
+}
+
+complete -o default -F _shef_completions shef
+`
+
+const zshCompletionScript = `#compdef shef
+# zsh completion for shef
+# Add to ~/.zshrc:  source <(shef completion zsh)
+
+_shef() {
+    local -a described unspaced
+    local line value desc
+    for line in "${(@f)$(shef __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z "$line" ]] && continue
+        value="${line%%$'\t'*}"
+        desc=""
+        [[ "$line" == *$'\t'* ]] && desc="${line#*$'\t'}"
+        if [[ "$value" == *= ]]; then
+            unspaced+=("${value//:/\\:}:$desc")
+        else
+            described+=("${value//:/\\:}:$desc")
+        fi
+    done
+
+    if (( ${#described} + ${#unspaced} == 0 )); then
+        _files
+        return
+    fi
+    (( ${#described} )) && _describe -t shef 'shef' described
+    (( ${#unspaced} )) && _describe -t shef-flags 'flags' unspaced -S ''
+    return 0
+}
+
+if [ "$funcstack[1]" = "_shef" ]; then
+    _shef "$@"
+else
+    compdef _shef shef
+fi
+`
+
+const fishCompletionScript = `# fish completion for shef
+# Add to ~/.config/fish/config.fish:  shef completion fish | source
+
+function __shef_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    shef __complete $tokens (commandline -ct) 2>/dev/null
+end
+
+complete -c shef -f -a '(__shef_complete)'
+`
+
+// handleCompletionCommand prints the completion script of a shell
+func handleCompletionCommand(c *cli.Context) error {
+	shell := c.Args().First()
+	if shell == "" {
+		return fmt.Errorf("you must specify a shell: bash, zsh or fish")
+	}
+
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q: use bash, zsh or fish", shell)
+	}
+
+	fmt.Print(script)
+	return nil
+}
+
+// handleCompleteCommand prints the completion candidates for the words of a command line. The last word is the one
+// being completed, and is empty when the cursor follows a space.
+func handleCompleteCommand(c *cli.Context) error {
+	catalogWarnings = io.Discard
+
+	for _, candidate := range completeArgs(c.App, c.Args().Slice()) {
+		if candidate.Description == "" {
+			fmt.Println(candidate.Value)
+		} else {
+			fmt.Printf("%s\t%s\n", candidate.Value, candidate.Description)
+		}
+	}
+	return nil
+}
+
+// completeArgs returns the completion candidates for the words that follow shef on a command line
+func completeArgs(app *cli.App, args []string) []completion {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	current := args[len(args)-1]
+	words := args[:len(args)-1]
+
+	valueFlags := flagsTakingValues(app.Flags)
+	var positionals []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if !strings.HasPrefix(word, "-") {
+			positionals = append(positionals, word)
+			continue
+		}
+		if len(positionals) == 0 && !strings.Contains(word, "=") && valueFlags[strings.TrimLeft(word, "-")] {
+			if i == len(words)-1 {
+				// the word being completed is the value of a global flag, such as a recipe file path
+				return nil
+			}
+			i++
+		}
+	}
+
+	if len(positionals) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return filterCompletions(flagCompletions(app.Flags), current)
+		}
+		return filterCompletions(append(commandCompletions(app.Commands), recipeTargetCompletions()...), current)
+	}
+
+	for _, cmd := range app.Commands {
+		if cmd.HasName(positionals[0]) {
+			return completeCommandArgs(cmd, positionals[1:], current)
+		}
+	}
+	return completeRecipeArgs(positionals, current)
+}
+
+// completeCommandArgs returns the completion candidates for the arguments of a shef command
+func completeCommandArgs(cmd *cli.Command, positionals []string, current string) []completion {
+	if strings.HasPrefix(current, "-") && len(positionals) == 0 {
+		return filterCompletions(flagCompletions(cmd.Flags), current)
+	}
+
+	var candidates []completion
+	switch cmd.Name {
+	case "list":
+		if len(positionals) == 0 {
+			candidates = categoryCompletions()
+		}
+	case "which", "run":
+		return completeRecipeArgs(positionals, current)
+	case "component":
+		candidates = componentCompletions(positionals, current)
+	case "logs", "kill":
+		if len(positionals) == 0 {
+			candidates = runCompletions()
+		}
+	case "completion":
+		if len(positionals) == 0 {
+			candidates = []completion{{Value: "bash"}, {Value: "fish"}, {Value: "zsh"}}
+		}
+	}
+	return filterCompletions(candidates, current)
+}
+
+// completeRecipeArgs completes a category, a recipe name, or the flags of the recipe named by the positionals
+func completeRecipeArgs(positionals []string, current string) []completion {
+	if len(positionals) > 0 {
+		if recipe := resolveCompletionRecipe(positionals); recipe != nil {
+			if strings.HasPrefix(current, "-") {
+				return recipeFlagCompletions(recipe, current)
+			}
+			return nil
+		}
+	}
+
+	if strings.HasPrefix(current, "-") {
+		return nil
+	}
+
+	switch len(positionals) {
+	case 0:
+		return filterCompletions(recipeTargetCompletions(), current)
+	case 1:
+		return filterCompletions(recipeCompletions(getRecipeCatalog().uniqueRecipes(recipeSources, positionals[0])), current)
+	}
+	return nil
+}
+
+// resolveCompletionRecipe loads the recipe named by the positionals, either as a recipe name or as a category
+// followed by a recipe name
+func resolveCompletionRecipe(positionals []string) *Recipe {
+	catalog := getRecipeCatalog()
+
+	summary, found := findRecipeSummary(catalog.uniqueRecipes(recipeSources, ""), positionals[0])
+	if !found && len(positionals) > 1 {
+		inCategory := catalog.uniqueRecipes(recipeSources, positionals[0])
+		summary, found = findRecipeSummary(inCategory, positionals[1])
+		if !found {
+			summary, found = findRecipeSummary(inCategory, fmt.Sprintf("%s-%s", positionals[0], positionals[1]))
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	recipe, err := summary.load()
+	if err != nil {
+		return nil
+	}
+	return recipe
+}
+
+// recipeFlagCompletions completes the flags a recipe declares through its prompts and variables, and the values of
+// prompts with fixed options
+func recipeFlagCompletions(recipe *Recipe, current string) []completion {
+	prompts := recipePrompts(recipe.Operations)
+
+	name, value, hasValue := strings.Cut(strings.TrimLeft(current, "-"), "=")
+	if hasValue {
+		prefix := current[:len(current)-len(value)]
+		for _, p := range prompts {
+			if strings.ReplaceAll(promptVarName(p), "-", "_") == strings.ReplaceAll(name, "-", "_") {
+				return filterCompletions(promptValueCompletions(p, prefix, value), current)
+			}
+		}
+		return nil
+	}
+
+	candidates := []completion{{Value: "--help", Description: "Show recipe help"}}
+	seen := make(map[string]bool)
+	for _, p := range prompts {
+		flag := promptVarName(p)
+		if !seen[flag] {
+			seen[flag] = true
+			candidates = append(candidates, completion{Value: "--" + flag + "=", Description: completionDescription(p.Message)})
+		}
+	}
+
+	varNames := make([]string, 0, len(recipe.Vars))
+	for varName := range recipe.Vars {
+		varNames = append(varNames, varName)
+	}
+	sort.Strings(varNames)
+	for _, varName := range varNames {
+		if !seen[varName] {
+			seen[varName] = true
+			candidates = append(candidates, completion{
+				Value:       "--" + varName + "=",
+				Description: completionDescription(fmt.Sprintf("default: %v", recipe.Vars[varName])),
+			})
+		}
+	}
+
+	return filterCompletions(candidates, current)
+}
+
+// recipePrompts returns the prompts of a recipe's operations, including nested operations and the fields of forms
+func recipePrompts(operations []Operation) []Prompt {
+	var prompts []Prompt
+	for _, op := range operations {
+		for _, p := range op.Prompts {
+			if p.Type == "form" {
+				prompts = append(prompts, p.Fields...)
+			} else {
+				prompts = append(prompts, p)
+			}
+		}
+		prompts = append(prompts, recipePrompts(op.Operations)...)
+	}
+	return prompts
+}
+
+// promptValueCompletions completes the value of a prompt flag from the prompt's fixed options. Values of prompts
+// that accept several options complete the item after the last comma.
+func promptValueCompletions(p Prompt, prefix, value string) []completion {
+	if p.Type == "confirm" {
+		return []completion{{Value: prefix + "true"}, {Value: prefix + "false"}}
+	}
+
+	switch p.Type {
+	case "select", "autocomplete", "fuzzy", "multiselect":
+	default:
+		return nil
+	}
+
+	chosen := make(map[string]bool)
+	if p.Type == "multiselect" || p.Multiple {
+		if i := strings.LastIndex(value, ","); i >= 0 {
+			for _, item := range strings.Split(value[:i], ",") {
+				chosen[strings.TrimSpace(item)] = true
+			}
+			prefix += value[:i+1]
+		}
+	}
+
+	var candidates []completion
+	for _, option := range p.Options {
+		if hasTemplateActions(option) || chosen[option] {
+			continue
+		}
+		candidates = append(candidates, completion{Value: prefix + option, Description: completionDescription(p.Descriptions[option])})
+	}
+	return candidates
+}
+
+// recipeTargetCompletions completes the first word of a recipe command: a category or a recipe name
+func recipeTargetCompletions() []completion {
+	return append(categoryCompletions(), recipeCompletions(getRecipeCatalog().uniqueRecipes(recipeSources, ""))...)
+}
+
+// categoryCompletions lists the recipe categories of every source
+func categoryCompletions() []completion {
+	counts := make(map[string]int)
+	var categories []string
+	for _, recipe := range getRecipeCatalog().uniqueRecipes(recipeSources, "") {
+		category := strings.ToLower(recipe.Category)
+		if category == "" {
+			continue
+		}
+		if counts[category] == 0 {
+			categories = append(categories, category)
+		}
+		counts[category]++
+	}
+	sort.Strings(categories)
+
+	candidates := make([]completion, len(categories))
+	for i, category := range categories {
+		description := fmt.Sprintf("category, %d recipes", counts[category])
+		if counts[category] == 1 {
+			description = "category, 1 recipe"
+		}
+		candidates[i] = completion{Value: category, Description: description}
+	}
+	return candidates
+}
+
+// recipeCompletions lists recipe names sorted by name
+func recipeCompletions(recipes []recipeSummary) []completion {
+	candidates := make([]completion, len(recipes))
+	for i, recipe := range recipes {
+		candidates[i] = completion{Value: recipe.Name, Description: completionDescription(recipe.Description)}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Value < candidates[j].Value
+	})
+	return candidates
+}
+
+// componentCompletions completes component IDs, or the inputs of the component named by the positionals
+func componentCompletions(positionals []string, current string) []completion {
+	loadComponents(recipeSources)
+
+	if len(positionals) == 0 {
+		var candidates []completion
+		for _, component := range globalComponentRegistry.List() {
+			candidates = append(candidates, completion{Value: component.ID, Description: completionDescription(component.Description)})
+		}
+		return candidates
+	}
+
+	component, exists := globalComponentRegistry.Get(positionals[0])
+	if !exists || !strings.HasPrefix(current, "-") {
+		return nil
+	}
+
+	var candidates []completion
+	for _, input := range component.Inputs {
+		candidates = append(candidates, completion{Value: "--" + input.ID + "=", Description: completionDescription(input.Description)})
+	}
+	return candidates
+}
+
+// runCompletions lists the IDs of detached runs
+func runCompletions() []completion {
+	var candidates []completion
+	for _, run := range listDetachedRuns() {
+		candidates = append(candidates, completion{
+			Value:       run.ID,
+			Description: completionDescription(fmt.Sprintf("%s (%s)", strings.Join(run.Args, " "), run.Status)),
+		})
+	}
+	return candidates
+}
+
+// commandCompletions lists the visible shef commands
+func commandCompletions(commands []*cli.Command) []completion {
+	var candidates []completion
+	for _, cmd := range commands {
+		if !cmd.Hidden {
+			candidates = append(candidates, completion{Value: cmd.Name, Description: cmd.Usage})
+		}
+	}
+	return candidates
+}
+
+// flagCompletions lists the long names of CLI flags
+func flagCompletions(flags []cli.Flag) []completion {
+	var candidates []completion
+	for _, flag := range flags {
+		usage := ""
+		if f, ok := flag.(cli.DocGenerationFlag); ok {
+			usage = f.GetUsage()
+		}
+		for _, name := range flag.Names() {
+			if len(name) > 1 {
+				candidates = append(candidates, completion{Value: "--" + name, Description: usage})
+			}
+		}
+	}
+	return candidates
+}
+
+// flagsTakingValues returns the names and aliases of the flags that take a value
+func flagsTakingValues(flags []cli.Flag) map[string]bool {
+	names := make(map[string]bool)
+	for _, flag := range flags {
+		if f, ok := flag.(cli.DocGenerationFlag); ok && f.TakesValue() {
+			for _, name := range flag.Names() {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+// filterCompletions keeps the candidates that start with the word being completed. When values repeat, such as a
+// recipe named like a command, the first candidate is kept.
+func filterCompletions(candidates []completion, current string) []completion {
+	var matches []completion
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate.Value, current) && !seen[candidate.Value] {
+			seen[candidate.Value] = true
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// completionDescription reduces a description to a single line that fits a completion menu
+func completionDescription(description string) string {
+	description, _, _ = strings.Cut(strings.TrimSpace(description), "\n")
+	return strings.ReplaceAll(strings.TrimSpace(description), "\t", " ")
+}
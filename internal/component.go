@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -35,6 +36,21 @@ func (cr *ComponentRegistry) Get(id string) (Component, bool) {
 	return component, exists
 }
 
+// List returns the registered components sorted by ID
+func (cr *ComponentRegistry) List() []Component {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	components := make([]Component, 0, len(cr.components))
+	for _, component := range cr.components {
+		components = append(components, component)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ID < components[j].ID
+	})
+	return components
+}
+
 // Clear empties the registry
 func (cr *ComponentRegistry) Clear() {
 	cr.mutex.Lock()
@@ -0,0 +1,773 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// condTokenKind identifies the kind of token produced by the condition lexer
+type condTokenKind int
+
+const (
+	condTokEOF condTokenKind = iota
+	condTokWord
+	condTokString
+	condTokOperator
+	condTokLParen
+	condTokRParen
+	condTokLBracket
+	condTokRBracket
+	condTokComma
+)
+
+// condToken is a single lexical token with its byte offset in the condition
+type condToken struct {
+	kind  condTokenKind
+	text  string
+	pos   int
+	quote bool
+}
+
+// conditionSyntaxError reports a problem in a condition together with the column it occurred at
+type conditionSyntaxError struct {
+	condition string
+	pos       int
+	message   string
+}
+
+func (e *conditionSyntaxError) Error() string {
+	return fmt.Sprintf("invalid condition at column %d: %s\n  %s\n  %s^",
+		e.pos+1, e.message, e.condition, strings.Repeat(" ", e.pos))
+}
+
+// conditionOperators lists multi and single character operators, longest first
+var conditionOperators = []string{"&&", "||", "==", "!=", ">=", "<=", "=~", "!~", ">", "<", "!"}
+
+// isConditionWordRune reports whether a rune can appear in an unquoted word
+func isConditionWordRune(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	return !strings.ContainsRune(`"'()[],=!<>&|~`, r)
+}
+
+// tokenizeCondition splits a condition into tokens
+func tokenizeCondition(condition string) ([]condToken, error) {
+	var tokens []condToken
+	runes := []rune(condition)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, condToken{kind: condTokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, condToken{kind: condTokRParen, text: ")", pos: i})
+			i++
+		case r == '[':
+			tokens = append(tokens, condToken{kind: condTokLBracket, text: "[", pos: i})
+			i++
+		case r == ']':
+			tokens = append(tokens, condToken{kind: condTokRBracket, text: "]", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, condToken{kind: condTokComma, text: ",", pos: i})
+			i++
+
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[i])
+					}
+					continue
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, &conditionSyntaxError{condition: condition, pos: start, message: "unterminated string literal"}
+			}
+			i++
+			tokens = append(tokens, condToken{kind: condTokString, text: sb.String(), pos: start, quote: true})
+
+		default:
+			matched := false
+			for _, op := range conditionOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, condToken{kind: condTokOperator, text: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+
+			if !isConditionWordRune(r) {
+				return nil, &conditionSyntaxError{condition: condition, pos: i, message: fmt.Sprintf("unexpected character '%c'", r)}
+			}
+
+			start := i
+			for i < len(runes) && isConditionWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: condTokWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+
+	tokens = append(tokens, condToken{kind: condTokEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// condNode is a node in a parsed condition expression
+type condNode interface {
+	eval(ctx *ExecutionContext) (interface{}, error)
+}
+
+type condLiteral struct {
+	value interface{}
+}
+
+type condVariable struct {
+	name string
+}
+
+type condOperationResult struct {
+	opID    string
+	success bool
+}
+
+type condNot struct {
+	operand condNode
+}
+
+type condLogical struct {
+	operator    string
+	left, right condNode
+}
+
+type condComparison struct {
+	operator    string
+	left, right condNode
+	pos         int
+	condition   string
+}
+
+type condList struct {
+	items []condNode
+}
+
+type condCall struct {
+	name      string
+	fn        conditionFunc
+	args      []condNode
+	pos       int
+	condition string
+}
+
+// conditionFunc is a function callable from a condition expression
+type conditionFunc func(ctx *ExecutionContext, args []interface{}) (interface{}, error)
+
+// conditionFunctions holds the functions available to condition expressions
+var conditionFunctions = buildConditionFunctions()
+
+// buildConditionFunctions assembles the condition function map from each category
+func buildConditionFunctions() map[string]conditionFunc {
+	funcs := map[string]conditionFunc{}
+
+	stringConditionFunctions(funcs)
+	fileConditionFunctions(funcs)
+	environmentConditionFunctions(funcs)
+	versionConditionFunctions(funcs)
+
+	return funcs
+}
+
+// stringConditionFunctions adds string matching functions to the condition function map
+func stringConditionFunctions(funcs map[string]conditionFunc) {
+	funcs["contains"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("contains", args, 2); err != nil {
+			return nil, err
+		}
+		return conditionContains(args[0], args[1]), nil
+	}
+	funcs["startsWith"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("startsWith", args, 2); err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(conditionString(args[0]), conditionString(args[1])), nil
+	}
+	funcs["endsWith"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("endsWith", args, 2); err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(conditionString(args[0]), conditionString(args[1])), nil
+	}
+}
+
+// expectConditionArgs checks that a condition function received the expected number of arguments
+func expectConditionArgs(name string, args []interface{}, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("%s expects %d argument(s), got %d", name, count, len(args))
+	}
+	return nil
+}
+
+// comparisonKeywords are word operators that compare two operands
+var comparisonKeywords = map[string]bool{
+	"in":         true,
+	"contains":   true,
+	"startsWith": true,
+	"endsWith":   true,
+}
+
+// condParser is a recursive descent parser for condition expressions
+type condParser struct {
+	condition string
+	tokens    []condToken
+	pos       int
+}
+
+// parseCondition parses a condition into an expression tree
+func parseCondition(condition string) (condNode, error) {
+	tokens, err := tokenizeCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &condParser{condition: condition, tokens: tokens}
+	if p.peek().kind == condTokEOF {
+		return condLiteral{value: true}, nil
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != condTokEOF {
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected '%s'", tok.text))
+	}
+
+	return node, nil
+}
+
+func (p *condParser) peek() condToken {
+	return p.tokens[p.pos]
+}
+
+func (p *condParser) next() condToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != condTokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *condParser) errorAt(tok condToken, message string) error {
+	return &conditionSyntaxError{condition: p.condition, pos: tok.pos, message: message}
+}
+
+func (p *condParser) isOperator(text string) bool {
+	tok := p.peek()
+	return tok.kind == condTokOperator && tok.text == text
+}
+
+// parseOr parses expressions joined by ||
+func (p *condParser) parseOr() (condNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = condLogical{operator: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses expressions joined by &&
+func (p *condParser) parseAnd() (condNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = condLogical{operator: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseNot parses a negation, which applies to the whole comparison that follows it
+func (p *condParser) parseNot() (condNode, error) {
+	if p.isOperator("!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return condNot{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses an operand optionally followed by a comparison operator and operand
+func (p *condParser) parseComparison() (condNode, error) {
+	left, err := p.parseOperand(false)
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	operator := ""
+	switch {
+	case tok.kind == condTokOperator && tok.text != "&&" && tok.text != "||" && tok.text != "!":
+		operator = tok.text
+		p.next()
+	case tok.kind == condTokWord && comparisonKeywords[tok.text]:
+		operator = tok.text
+		p.next()
+	case tok.kind == condTokWord && tok.text == "not":
+		p.next()
+		if in := p.peek(); in.kind != condTokWord || in.text != "in" {
+			return nil, p.errorAt(in, "expected 'in' after 'not'")
+		}
+		p.next()
+		operator = "not in"
+	default:
+		return left, nil
+	}
+
+	// as in earlier releases, the unquoted right-hand side of a comparison is a literal, so .env == prod compares with
+	// the text prod even when a variable named prod exists
+	right, err := p.parseOperand(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return condComparison{operator: operator, left: left, right: right, pos: tok.pos, condition: p.condition}, nil
+}
+
+// parseOperand parses a literal, variable, list, function call or parenthesized expression. With literalWords,
+// unquoted words are literal text and consecutive words form a single value, such as hello world.
+func (p *condParser) parseOperand(literalWords bool) (condNode, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case condTokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != condTokRParen {
+			return nil, p.errorAt(closing, "expected ')'")
+		}
+		return node, nil
+
+	case condTokLBracket:
+		var items []condNode
+		if p.peek().kind == condTokRBracket {
+			p.next()
+			return condList{items: items}, nil
+		}
+		for {
+			item, err := p.parseOperand(true)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+
+			sep := p.next()
+			if sep.kind == condTokRBracket {
+				return condList{items: items}, nil
+			}
+			if sep.kind != condTokComma {
+				return nil, p.errorAt(sep, "expected ',' or ']' in list")
+			}
+		}
+
+	case condTokString:
+		return condLiteral{value: tok.text}, nil
+
+	case condTokWord:
+		if p.peek().kind == condTokLParen {
+			return p.parseCall(tok)
+		}
+		if literalWords && !strings.HasPrefix(tok.text, "$") && !strings.HasPrefix(tok.text, ".") {
+			return p.parseLiteralWords(tok), nil
+		}
+		return wordNode(tok.text), nil
+
+	case condTokEOF:
+		return nil, p.errorAt(tok, "unexpected end of condition")
+
+	default:
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected '%s'", tok.text))
+	}
+}
+
+// parseCall parses a function call such as contains(.name, "x")
+func (p *condParser) parseCall(name condToken) (condNode, error) {
+	fn, ok := conditionFunctions[name.text]
+	if !ok {
+		return nil, p.errorAt(name, fmt.Sprintf("unknown function '%s'", name.text))
+	}
+
+	p.next()
+	call := condCall{name: name.text, fn: fn, pos: name.pos, condition: p.condition}
+	if p.peek().kind == condTokRParen {
+		p.next()
+		return call, nil
+	}
+
+	for {
+		arg, err := p.parseCallArgument()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+
+		sep := p.next()
+		if sep.kind == condTokRParen {
+			return call, nil
+		}
+		if sep.kind != condTokComma {
+			return nil, p.errorAt(sep, "expected ',' or ')' in function call")
+		}
+	}
+}
+
+// parseCallArgument parses a function argument. As on the right-hand side of a comparison, an argument that is a
+// single unquoted word is literal text, so hasCommand(git) and env(HOME) name a command and an environment variable.
+func (p *condParser) parseCallArgument() (condNode, error) {
+	tok := p.peek()
+	if tok.kind == condTokWord && !strings.HasPrefix(tok.text, "$") && !strings.HasPrefix(tok.text, ".") {
+		if after := p.tokens[p.pos+1]; after.kind == condTokComma || after.kind == condTokRParen {
+			return p.parseLiteralWords(p.next()), nil
+		}
+	}
+	return p.parseOr()
+}
+
+// parseLiteralWords reads an unquoted literal that starts with the given word and runs until the next token that is
+// not a word, keeping the original spacing between words
+func (p *condParser) parseLiteralWords(first condToken) condNode {
+	last := first
+	for p.peek().kind == condTokWord {
+		last = p.next()
+	}
+
+	if last == first {
+		switch first.text {
+		case "true":
+			return condLiteral{value: true}
+		case "false":
+			return condLiteral{value: false}
+		}
+		return condLiteral{value: first.text}
+	}
+
+	runes := []rune(p.condition)
+	return condLiteral{value: string(runes[first.pos : last.pos+len([]rune(last.text))])}
+}
+
+// wordNode converts an unquoted word into a literal, variable or operation result reference
+func wordNode(word string) condNode {
+	switch word {
+	case "true":
+		return condLiteral{value: true}
+	case "false":
+		return condLiteral{value: false}
+	}
+
+	if strings.HasPrefix(word, "$") || strings.HasPrefix(word, ".") {
+		return condVariable{name: normalizeVariableName(word)}
+	}
+
+	if opID, found := strings.CutSuffix(word, ".success"); found && opID != "" && !strings.Contains(opID, ".") {
+		return condOperationResult{opID: opID, success: true}
+	}
+	if opID, found := strings.CutSuffix(word, ".failure"); found && opID != "" && !strings.Contains(opID, ".") {
+		return condOperationResult{opID: opID, success: false}
+	}
+
+	if _, err := strconv.ParseFloat(word, 64); err == nil {
+		return condLiteral{value: word}
+	}
+
+	return condVariable{name: word}
+}
+
+func (n condLiteral) eval(ctx *ExecutionContext) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n condVariable) eval(ctx *ExecutionContext) (interface{}, error) {
+	if value, ok := resolveConditionVariable(n.name, ctx); ok {
+		return value, nil
+	}
+	if constant, ok := conditionConstants[n.name]; ok {
+		return constant(), nil
+	}
+	// an undefined variable is false, as in earlier releases
+	return "false", nil
+}
+
+func (n condOperationResult) eval(ctx *ExecutionContext) (interface{}, error) {
+	result, exists := ctx.OperationResults[n.opID]
+	if n.success {
+		return exists && result, nil
+	}
+	return !exists || !result, nil
+}
+
+func (n condNot) eval(ctx *ExecutionContext) (interface{}, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return !conditionTruthy(value), nil
+}
+
+func (n condLogical) eval(ctx *ExecutionContext) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leftTrue := conditionTruthy(left)
+	if n.operator == "&&" && !leftTrue {
+		return false, nil
+	}
+	if n.operator == "||" && leftTrue {
+		return true, nil
+	}
+
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conditionTruthy(right), nil
+}
+
+func (n condList) eval(ctx *ExecutionContext) (interface{}, error) {
+	items := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+func (n condCall) eval(ctx *ExecutionContext) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	result, err := n.fn(ctx, args)
+	if err != nil {
+		return nil, &conditionSyntaxError{condition: n.condition, pos: n.pos, message: err.Error()}
+	}
+	return result, nil
+}
+
+func (n condComparison) eval(ctx *ExecutionContext) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	Log(CategoryCondition, fmt.Sprintf("Comparing: '%s' %s '%s'", conditionString(left), n.operator, conditionString(right)))
+
+	switch n.operator {
+	case "==":
+		return conditionEqual(left, right), nil
+	case "!=":
+		return !conditionEqual(left, right), nil
+	case ">", "<", ">=", "<=":
+		return compareConditionValues(left, right, n.operator), nil
+	case "=~", "!~":
+		re, err := regexp.Compile(conditionString(right))
+		if err != nil {
+			return nil, &conditionSyntaxError{condition: n.condition, pos: n.pos, message: fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		matched := re.MatchString(conditionString(left))
+		return matched == (n.operator == "=~"), nil
+	case "in":
+		return conditionContains(right, left), nil
+	case "not in":
+		return !conditionContains(right, left), nil
+	case "contains":
+		return conditionContains(left, right), nil
+	case "startsWith":
+		return strings.HasPrefix(conditionString(left), conditionString(right)), nil
+	case "endsWith":
+		return strings.HasSuffix(conditionString(left), conditionString(right)), nil
+	default:
+		return nil, &conditionSyntaxError{condition: n.condition, pos: n.pos, message: fmt.Sprintf("unsupported operator '%s'", n.operator)}
+	}
+}
+
+// conditionString converts a condition value to its string form
+func conditionString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// conditionTruthy reports whether a value counts as true in a condition
+func conditionTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		s := strings.TrimSpace(v)
+		return s != "" && s != "false" && s != "0"
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() > 0
+	default:
+		return conditionTruthy(conditionString(value))
+	}
+}
+
+// conditionNumber parses a value as a number, treating false as zero like earlier releases
+func conditionNumber(value interface{}) (float64, bool) {
+	s := strings.TrimSpace(conditionString(value))
+	if s == "false" {
+		s = "0"
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// conditionEqual compares two values numerically when both are numbers and as strings otherwise
+func conditionEqual(left, right interface{}) bool {
+	if cmp, ok := compareSemverOperands(left, right); ok {
+		return cmp == 0
+	}
+	if _, isBool := left.(bool); !isBool {
+		if _, isBool := right.(bool); !isBool {
+			leftNum, leftOk := parseConditionFloat(left)
+			rightNum, rightOk := parseConditionFloat(right)
+			if leftOk && rightOk {
+				return leftNum == rightNum
+			}
+		}
+	}
+	return conditionString(left) == conditionString(right)
+}
+
+// parseConditionFloat parses a value as a number without any boolean normalization
+func parseConditionFloat(value interface{}) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(conditionString(value)), 64)
+	return f, err == nil
+}
+
+// compareConditionValues orders two values numerically when possible and lexically otherwise
+func compareConditionValues(left, right interface{}, operator string) bool {
+	var cmp int
+	leftNum, leftOk := conditionNumber(left)
+	rightNum, rightOk := conditionNumber(right)
+	versionCmp, isVersion := compareSemverOperands(left, right)
+
+	switch {
+	case isVersion:
+		cmp = versionCmp
+	case leftOk && rightOk:
+		switch {
+		case leftNum < rightNum:
+			cmp = -1
+		case leftNum > rightNum:
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(conditionString(left), conditionString(right))
+	}
+
+	switch operator {
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// conditionContains reports whether a collection holds an item, a map has a key, or a string has a substring
+func conditionContains(collection, item interface{}) bool {
+	if m, ok := collection.(map[string]interface{}); ok {
+		_, exists := m[conditionString(item)]
+		return exists
+	}
+
+	rv := reflect.ValueOf(collection)
+	if collection != nil && rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			if conditionEqual(rv.Index(i).Interface(), item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return strings.Contains(conditionString(collection), conditionString(item))
+}
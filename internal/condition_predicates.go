@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// conditionConstants are bare words that evaluate to platform facts when no variable shadows them
+var conditionConstants = map[string]func() interface{}{
+	"os":    func() interface{} { return runtime.GOOS },
+	"arch":  func() interface{} { return runtime.GOARCH },
+	"isTTY": func() interface{} { return isTerminal() },
+}
+
+// fileConditionFunctions adds file system predicates to the condition function map
+func fileConditionFunctions(funcs map[string]conditionFunc) {
+	funcs["exists"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("exists", args, 1); err != nil {
+			return nil, err
+		}
+		_, err := os.Stat(conditionPath(args[0], ctx))
+		return err == nil, nil
+	}
+	funcs["isDir"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("isDir", args, 1); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(conditionPath(args[0], ctx))
+		return err == nil && info.IsDir(), nil
+	}
+	funcs["isFile"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("isFile", args, 1); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(conditionPath(args[0], ctx))
+		return err == nil && info.Mode().IsRegular(), nil
+	}
+	funcs["changedSince"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("changedSince", args, 2); err != nil {
+			return nil, err
+		}
+		window, err := parseDurationWithDays(conditionString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(conditionPath(args[0], ctx))
+		if err != nil {
+			return false, nil
+		}
+		return time.Since(info.ModTime()) <= window, nil
+	}
+}
+
+// environmentConditionFunctions adds environment and platform predicates to the condition function map
+func environmentConditionFunctions(funcs map[string]conditionFunc) {
+	funcs["hasCommand"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("hasCommand", args, 1); err != nil {
+			return nil, err
+		}
+		_, err := exec.LookPath(conditionString(args[0]))
+		return err == nil, nil
+	}
+	funcs["env"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("env", args, 1); err != nil {
+			return nil, err
+		}
+		return os.Getenv(conditionString(args[0])), nil
+	}
+	funcs["isTTY"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("isTTY", args, 0); err != nil {
+			return nil, err
+		}
+		return isTerminal(), nil
+	}
+}
+
+// conditionPath expands a leading ~ and resolves relative paths against the recipe working directory
+func conditionPath(value interface{}, ctx *ExecutionContext) string {
+	path := conditionString(value)
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+
+	if !filepath.IsAbs(path) {
+		if workdir, ok := ctx.Vars["workdir"]; ok {
+			path = filepath.Join(conditionString(workdir), path)
+		}
+	}
+
+	return path
+}
+
+// isTerminal reports whether shef is attached to an interactive terminal
+func isTerminal() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -6,5 +6,7 @@ const (
 	GithubRepo            = "https://github.com/eduardoagarcia/shef"
 	PublicRecipesFilename = "recipes.tar.gz"
 	PublicRecipesFolder   = "recipes"
+	StdinNone             = "none"
+	StdinPrevious         = "previous"
 	Version               = "v0.3.3"
 )
@@ -1,12 +1,54 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 )
 
+// Loop error policies control how a failing sub-operation affects the loop
+const (
+	LoopOnErrorContinue = "continue"
+	LoopOnErrorBreak    = "break"
+	LoopOnErrorFail     = "fail"
+)
+
+// loopFailedError is returned by a loop whose on_error policy is fail. Enclosing loops without a policy of their own
+// pass it on, so the failure reaches the recipe instead of ending only the current iteration.
+type loopFailedError struct {
+	err error
+}
+
+func (e *loopFailedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *loopFailedError) Unwrap() error {
+	return e.err
+}
+
+// parseLoopOnError extracts and validates the on_error policy from a control flow map
+func parseLoopOnError(flowMap map[string]interface{}) (string, error) {
+	onErrorVal, ok := flowMap["on_error"]
+	if !ok {
+		return "", nil
+	}
+
+	onError, ok := onErrorVal.(string)
+	if !ok {
+		return "", fmt.Errorf("on_error must be a string")
+	}
+
+	switch onError {
+	case "", LoopOnErrorContinue, LoopOnErrorBreak, LoopOnErrorFail:
+		return onError, nil
+	default:
+		return "", fmt.Errorf("invalid on_error value: %s (expected continue, break or fail)", onError)
+	}
+}
+
 // executeLoopOperations runs all operations for a single iteration.
 func executeLoopOperations(operations []Operation, ctx *ExecutionContext, depth int,
-	executeOp func(Operation, int) (bool, error)) (exit bool, breakLoop bool) {
+	executeOp func(Operation, int) (bool, error)) (exit bool, breakLoop bool, err error) {
 
 	for _, subOp := range operations {
 		if !shouldRunOperation(subOp, ctx) {
@@ -15,21 +57,67 @@ func executeLoopOperations(operations []Operation, ctx *ExecutionContext, depth
 
 		shouldExit, err := executeOp(subOp, depth+1)
 		if err != nil {
-			return shouldExit, false
+			return handleLoopOperationError(subOp, ctx, shouldExit, err)
 		}
 
 		if shouldExit || subOp.Exit {
 			Log(CategoryControlFlow, fmt.Sprintf("Exiting entire recipe due to exit flag in '%s'", subOp.Name))
-			return true, false
+			return true, false, nil
 		}
 
 		if subOp.Break {
 			Log(CategoryControlFlow, fmt.Sprintf("Breaking out of loop due to break flag in '%s'", subOp.Name))
-			return false, true
+			return false, true, nil
+		}
+
+		if subOp.Continue {
+			Log(CategoryControlFlow, fmt.Sprintf("Skipping to next iteration due to continue flag in '%s'", subOp.Name))
+			return false, false, nil
 		}
 	}
 
-	return false, false
+	return false, false, nil
+}
+
+// handleLoopOperationError applies the current loop's on_error policy to a failed sub-operation
+func handleLoopOperationError(subOp Operation, ctx *ExecutionContext, shouldExit bool, err error) (bool, bool, error) {
+	loop := ctx.currentLoop()
+	if loop == nil || loop.OnError == "" {
+		var failed *loopFailedError
+		if errors.As(err, &failed) {
+			return false, false, err
+		}
+		return shouldExit, false, nil
+	}
+
+	iteration := 0
+	if val, ok := ctx.Vars["iteration"].(int); ok {
+		iteration = val
+	}
+
+	switch loop.OnError {
+	case LoopOnErrorFail:
+		Log(CategoryControlFlow, fmt.Sprintf("Failing loop at iteration %d due to error in '%s'", iteration, subOp.Name))
+		return false, false, &loopFailedError{
+			err: fmt.Errorf("loop failed at iteration %d in operation '%s': %w", iteration, subOp.Name, err),
+		}
+	case LoopOnErrorBreak:
+		loop.FailedIterations = append(loop.FailedIterations, iteration)
+		Log(CategoryControlFlow, fmt.Sprintf("Breaking out of loop at iteration %d due to error in '%s'", iteration, subOp.Name))
+		return false, true, nil
+	default:
+		loop.FailedIterations = append(loop.FailedIterations, iteration)
+		Log(CategoryControlFlow, fmt.Sprintf("Continuing loop after error at iteration %d in '%s'", iteration, subOp.Name))
+		return false, false, nil
+	}
+}
+
+// resetFailedIterations clears the failed iterations recorded by an earlier loop. It is called once a loop with an
+// on_error policy has resolved what it iterates over, so the loop can still iterate over the earlier failures.
+func resetFailedIterations(ctx *ExecutionContext, loop *LoopContext) {
+	if loop.OnError != "" {
+		ctx.deleteVar("failed_iterations")
+	}
 }
 
 // setupProgressMode configures progress mode for control flow execution.
@@ -43,8 +131,12 @@ func setupProgressMode(ctx *ExecutionContext, useProgressMode bool) (originalMod
 
 // cleanupLoopState removes loop variables and sets operation result.
 func cleanupLoopState(ctx *ExecutionContext, opID string, varName string) {
-	delete(ctx.Vars, varName)
-	delete(ctx.Vars, "iteration")
+	ctx.deleteVar(varName)
+	ctx.deleteVar("iteration")
+
+	if loop := ctx.currentLoop(); loop != nil && loop.OnError != "" {
+		ctx.setVar("failed_iterations", loop.FailedIterations)
+	}
 
 	if opID != "" {
 		ctx.OperationResults[opID] = true
@@ -11,6 +11,7 @@ type ForFlow struct {
 	Count           string              `yaml:"count"`
 	Variable        string              `yaml:"variable"`
 	ProgressMode    bool                `yaml:"progress_mode,omitempty"`
+	OnError         string              `yaml:"on_error,omitempty"`
 	ProgressBar     bool                `yaml:"progress_bar,omitempty"`
 	ProgressBarOpts *ProgressBarOptions `yaml:"progress_bar_options,omitempty"`
 }
@@ -43,6 +44,11 @@ func (op *Operation) GetForFlow() (*ForFlow, error) {
 	}
 
 	progressMode, _ := flowMap["progress_mode"].(bool)
+
+	onError, err := parseLoopOnError(flowMap)
+	if err != nil {
+		return nil, err
+	}
 	progressBar, _ := flowMap["progress_bar"].(bool)
 
 	var progressBarOpts *ProgressBarOptions
@@ -57,6 +63,7 @@ func (op *Operation) GetForFlow() (*ForFlow, error) {
 		Count:           count,
 		Variable:        variable,
 		ProgressMode:    progressMode,
+		OnError:         onError,
 		ProgressBar:     progressBar,
 		ProgressBarOpts: progressBarOpts,
 	}, nil
@@ -66,6 +73,7 @@ func (op *Operation) GetForFlow() (*ForFlow, error) {
 func ExecuteFor(op Operation, forFlow *ForFlow, ctx *ExecutionContext, depth int, executeOp func(Operation, int) (bool, error)) (bool, error) {
 	loopCtx := ctx.pushLoopContext("for", depth)
 	defer ctx.popLoopContext()
+	loopCtx.OnError = forFlow.OnError
 
 	originalMode := setupProgressMode(ctx, forFlow.ProgressMode)
 	defer func() {
@@ -79,6 +87,7 @@ func ExecuteFor(op Operation, forFlow *ForFlow, ctx *ExecutionContext, depth int
 	if err != nil {
 		return false, err
 	}
+	resetFailedIterations(ctx, loopCtx)
 
 	Log(CategoryLoop, fmt.Sprintf("For loop with %d iterations", count))
 
@@ -93,8 +102,8 @@ func ExecuteFor(op Operation, forFlow *ForFlow, ctx *ExecutionContext, depth int
 
 	for i := 0; i < count; i++ {
 		ctx.updateLoopDuration()
-		ctx.Vars[forFlow.Variable] = i
-		ctx.Vars["iteration"] = i + 1
+		ctx.setVar(forFlow.Variable, i)
+		ctx.setVar("iteration", i+1)
 
 		LogLoopIteration("for", i+1, count, map[string]interface{}{
 			"variable": forFlow.Variable,
@@ -109,12 +118,19 @@ func ExecuteFor(op Operation, forFlow *ForFlow, ctx *ExecutionContext, depth int
 			}
 		}
 
-		exit, breakLoop := executeLoopOperations(op.Operations, ctx, depth, executeOp)
+		exit, breakLoop, err := executeLoopOperations(op.Operations, ctx, depth, executeOp)
 
 		if progressBar != nil {
 			progressBar.Increment()
 		}
 
+		if err != nil {
+			if progressBar != nil {
+				progressBar.Complete()
+			}
+			return false, err
+		}
+
 		if exit {
 			if progressBar != nil {
 				progressBar.Complete()
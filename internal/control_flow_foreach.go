@@ -2,6 +2,8 @@ package internal
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 )
 
 // ForEachFlow defines the structure for a foreach loop control flow
@@ -10,6 +12,7 @@ type ForEachFlow struct {
 	Collection      string              `yaml:"collection"`
 	As              string              `yaml:"as"`
 	ProgressMode    bool                `yaml:"progress_mode,omitempty"`
+	OnError         string              `yaml:"on_error,omitempty"`
 	ProgressBar     bool                `yaml:"progress_bar,omitempty"`
 	ProgressBarOpts *ProgressBarOptions `yaml:"progress_bar_options,omitempty"`
 }
@@ -46,6 +49,11 @@ func (op *Operation) GetForEachFlow() (*ForEachFlow, error) {
 	}
 
 	progressMode, _ := flowMap["progress_mode"].(bool)
+
+	onError, err := parseLoopOnError(flowMap)
+	if err != nil {
+		return nil, err
+	}
 	progressBar, _ := flowMap["progress_bar"].(bool)
 
 	var progressBarOpts *ProgressBarOptions
@@ -60,6 +68,7 @@ func (op *Operation) GetForEachFlow() (*ForEachFlow, error) {
 		Collection:      collection,
 		As:              as,
 		ProgressMode:    progressMode,
+		OnError:         onError,
 		ProgressBar:     progressBar,
 		ProgressBarOpts: progressBarOpts,
 	}, nil
@@ -69,6 +78,7 @@ func (op *Operation) GetForEachFlow() (*ForEachFlow, error) {
 func ExecuteForEach(op Operation, forEach *ForEachFlow, ctx *ExecutionContext, depth int, executeOp func(Operation, int) (bool, error)) (bool, error) {
 	loopCtx := ctx.pushLoopContext("foreach", depth)
 	defer ctx.popLoopContext()
+	loopCtx.OnError = forEach.OnError
 
 	originalMode := setupProgressMode(ctx, forEach.ProgressMode)
 	defer func() {
@@ -78,12 +88,11 @@ func ExecuteForEach(op Operation, forEach *ForEachFlow, ctx *ExecutionContext, d
 		}
 	}()
 
-	collectionExpr, err := renderTemplate(forEach.Collection, ctx.templateVars())
+	items, err := resolveForEachItems(forEach.Collection, ctx)
 	if err != nil {
-		return false, fmt.Errorf("failed to render collection template: %w", err)
+		return false, err
 	}
-
-	items := parseOptionsFromOutput(collectionExpr)
+	resetFailedIterations(ctx, loopCtx)
 
 	Log(CategoryLoop, fmt.Sprintf("Foreach loop over %d items", len(items)))
 
@@ -98,8 +107,8 @@ func ExecuteForEach(op Operation, forEach *ForEachFlow, ctx *ExecutionContext, d
 
 	for idx, item := range items {
 		ctx.updateLoopDuration()
-		ctx.Vars[forEach.As] = item
-		ctx.Vars["iteration"] = idx + 1
+		ctx.setVar(forEach.As, item)
+		ctx.setVar("iteration", idx+1)
 
 		LogLoopIteration("foreach", idx+1, len(items), map[string]interface{}{
 			"variable": forEach.As,
@@ -114,12 +123,19 @@ func ExecuteForEach(op Operation, forEach *ForEachFlow, ctx *ExecutionContext, d
 			}
 		}
 
-		exit, breakLoop := executeLoopOperations(op.Operations, ctx, depth, executeOp)
+		exit, breakLoop, err := executeLoopOperations(op.Operations, ctx, depth, executeOp)
 
 		if progressBar != nil {
 			progressBar.Increment()
 		}
 
+		if err != nil {
+			if progressBar != nil {
+				progressBar.Complete()
+			}
+			return false, err
+		}
+
 		if exit {
 			if progressBar != nil {
 				progressBar.Complete()
@@ -140,3 +156,47 @@ func ExecuteForEach(op Operation, forEach *ForEachFlow, ctx *ExecutionContext, d
 
 	return false, nil
 }
+
+var collectionRefPattern = regexp.MustCompile(`^\{\{-?\s*\.([A-Za-z_][\w]*(?:\.[\w]+)*)\s*-?\}\}$`)
+
+// resolveForEachItems returns the items of a foreach collection. A collection that is a single
+// variable reference to a list or map is iterated directly, anything else is rendered and split into lines.
+func resolveForEachItems(collection string, ctx *ExecutionContext) ([]interface{}, error) {
+	vars := ctx.templateVars()
+
+	if matches := collectionRefPattern.FindStringSubmatch(strings.TrimSpace(collection)); matches != nil {
+		if value, ok := lookupVarPath(vars, matches[1]); ok {
+			if items, ok := collectionItems(value); ok {
+				return items, nil
+			}
+		}
+	}
+
+	collectionExpr, err := renderTemplate(collection, vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render collection template: %w", err)
+	}
+
+	lines := parseOptionsFromOutput(collectionExpr)
+	items := make([]interface{}, len(lines))
+	for i, line := range lines {
+		items[i] = line
+	}
+	return items, nil
+}
+
+// lookupVarPath resolves a dotted path such as inspect.items against template variables
+func lookupVarPath(vars map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = vars
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
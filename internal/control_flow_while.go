@@ -9,6 +9,7 @@ type WhileFlow struct {
 	Type         string `yaml:"type"`
 	Condition    string `yaml:"condition"`
 	ProgressMode bool   `yaml:"progress_mode,omitempty"`
+	OnError      string `yaml:"on_error,omitempty"`
 }
 
 // GetType returns the control flow type
@@ -39,10 +40,16 @@ func (op *Operation) GetWhileFlow() (*WhileFlow, error) {
 
 	progressMode, _ := flowMap["progress_mode"].(bool)
 
+	onError, err := parseLoopOnError(flowMap)
+	if err != nil {
+		return nil, err
+	}
+
 	return &WhileFlow{
 		Type:         "while",
 		Condition:    condition,
 		ProgressMode: progressMode,
+		OnError:      onError,
 	}, nil
 }
 
@@ -50,6 +57,7 @@ func (op *Operation) GetWhileFlow() (*WhileFlow, error) {
 func ExecuteWhile(op Operation, whileFlow *WhileFlow, ctx *ExecutionContext, depth int, executeOp func(Operation, int) (bool, error)) (bool, error) {
 	loopCtx := ctx.pushLoopContext("while", depth)
 	defer ctx.popLoopContext()
+	loopCtx.OnError = whileFlow.OnError
 
 	originalMode := setupProgressMode(ctx, whileFlow.ProgressMode)
 	defer func() {
@@ -72,16 +80,22 @@ func ExecuteWhile(op Operation, whileFlow *WhileFlow, ctx *ExecutionContext, dep
 		if !shouldContinue {
 			break
 		}
+		if iterations == 0 {
+			resetFailedIterations(ctx, loopCtx)
+		}
 
 		iterations++
-		ctx.Vars["iteration"] = iterations
+		ctx.setVar("iteration", iterations)
 
 		LogLoopIteration("while", iterations, -1, map[string]interface{}{
 			"condition": whileFlow.Condition,
 			"duration":  formatDuration(loopCtx.Duration),
 		})
 
-		exit, breakLoop := executeLoopOperations(op.Operations, ctx, depth, executeOp)
+		exit, breakLoop, err := executeLoopOperations(op.Operations, ctx, depth, executeOp)
+		if err != nil {
+			return false, err
+		}
 		if exit {
 			return true, nil
 		}
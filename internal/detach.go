@@ -0,0 +1,482 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+)
+
+// DetachedRunEnv tells a child process which detached run it is executing
+const DetachedRunEnv = "SHEF_DETACHED_RUN_ID"
+
+// Detached run status values
+const (
+	RunStarting = "starting"
+	RunRunning  = "running"
+	RunComplete = "complete"
+	RunFailed   = "failed"
+	RunKilled   = "killed"
+	RunExited   = "exited"
+)
+
+// detachedRun describes a recipe run launched with shef run --detach
+type detachedRun struct {
+	ID         string     `json:"id"`
+	Args       []string   `json:"args"`
+	PID        int        `json:"pid"`
+	Workdir    string     `json:"workdir"`
+	StartedAt  time.Time  `json:"started_at"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// detachedRunResult is written once a detached run finishes or is killed
+type detachedRunResult struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// getDetachedRoot returns the directory holding detached run records
+func getDetachedRoot() string {
+	return filepath.Join(getXDGDataHome(), "shef", "detached")
+}
+
+// detachedRunPaths returns the record, result and output file paths for a run
+func detachedRunPaths(id string) (record, result, output string) {
+	dir := filepath.Join(getDetachedRoot(), id)
+	return filepath.Join(dir, "run.json"), filepath.Join(dir, "result.json"), filepath.Join(dir, "output.log")
+}
+
+// handleRunCommand runs a recipe in the foreground or launches it as a detached child
+func handleRunCommand(c *cli.Context) error {
+	if c.Bool("detach") {
+		return startDetachedRun(c)
+	}
+
+	args := c.Args().Slice()
+	if len(args) == 0 && !c.IsSet("recipe-file") {
+		if dispatched, err := dispatchShadowedRecipe(c); dispatched {
+			return err
+		}
+		return fmt.Errorf("no recipe specified. Use shef ls to list available recipes")
+	}
+
+	debugger := setupDebugging(c)
+	defer debugger()
+
+	err := dispatch(c, args, getSourcePriority(c))
+
+	if runID := os.Getenv(DetachedRunEnv); runID != "" {
+		recordDetachedRunResult(runID, err)
+	}
+
+	return err
+}
+
+// startDetachedRun launches the recipe as a background child process and records it
+func startDetachedRun(c *cli.Context) error {
+	args := c.Args().Slice()
+	if len(args) == 0 && !c.IsSet("recipe-file") {
+		return fmt.Errorf("no recipe specified. Use shef ls to list available recipes")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate shef executable: %w", err)
+	}
+
+	workdir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to determine working directory: %w", err)
+	}
+
+	run := detachedRun{
+		ID:        uuid.New().String(),
+		Args:      args,
+		Workdir:   workdir,
+		StartedAt: time.Now(),
+	}
+
+	recordPath, _, outputPath := detachedRunPaths(run.ID)
+	if err := os.MkdirAll(filepath.Dir(recordPath), 0755); err != nil {
+		return fmt.Errorf("failed to create detached run directory: %w", err)
+	}
+
+	outputFile, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create detached run output file: %w", err)
+	}
+	defer safeClose(outputFile, "detached run output")
+
+	childArgs := append(forwardedGlobalFlags(c), "run")
+	childArgs = append(childArgs, args...)
+
+	cmd := exec.Command(executable, childArgs...)
+	cmd.Dir = workdir
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", DetachedRunEnv, run.ID))
+	cmd.Stdout = outputFile
+	cmd.Stderr = outputFile
+	cmd.SysProcAttr = detachedProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start detached run: %w", err)
+	}
+
+	run.PID = cmd.Process.Pid
+	if err := writeJSONFile(recordPath, run); err != nil {
+		return err
+	}
+
+	if err := cmd.Process.Release(); err != nil {
+		LogError("Failed to release detached process", err, nil)
+	}
+
+	fmt.Println(run.ID)
+	return nil
+}
+
+// forwardedGlobalFlags rebuilds the global flags that were set so a detached child behaves the same
+func forwardedGlobalFlags(c *cli.Context) []string {
+	var flags []string
+	for _, flag := range globalFlags() {
+		name := flag.Names()[0]
+		if !c.IsSet(name) {
+			continue
+		}
+
+		switch flag.(type) {
+		case *cli.BoolFlag:
+			if c.Bool(name) {
+				flags = append(flags, "--"+name)
+			}
+		default:
+			flags = append(flags, fmt.Sprintf("--%s=%s", name, c.String(name)))
+		}
+	}
+	return flags
+}
+
+// recordDetachedRunResult stores the final status of a detached run from inside the child
+func recordDetachedRunResult(id string, runErr error) {
+	result := detachedRunResult{
+		Status:     RunComplete,
+		FinishedAt: time.Now(),
+	}
+	if runErr != nil {
+		result.Status = RunFailed
+		result.Error = runErr.Error()
+	}
+
+	_, resultPath, _ := detachedRunPaths(id)
+	if err := writeJSONFile(resultPath, result); err != nil {
+		LogError("Failed to record detached run result", err, nil)
+	}
+}
+
+// writeJSONFile marshals a value to an indented JSON file
+func writeJSONFile(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadDetachedRun reads a run record and resolves its current status
+func loadDetachedRun(id string) (*detachedRun, error) {
+	recordPath, resultPath, _ := detachedRunPaths(id)
+
+	data, err := os.ReadFile(recordPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var run detachedRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("invalid detached run record %s: %w", recordPath, err)
+	}
+
+	if data, err := os.ReadFile(resultPath); err == nil {
+		var result detachedRunResult
+		if err := json.Unmarshal(data, &result); err == nil {
+			run.Status = result.Status
+			run.Error = result.Error
+			run.FinishedAt = &result.FinishedAt
+			return &run, nil
+		}
+	}
+
+	switch {
+	case run.PID == 0:
+		run.Status = RunStarting
+	case isProcessRunning(run.PID):
+		run.Status = RunRunning
+	default:
+		run.Status = RunExited
+	}
+
+	return &run, nil
+}
+
+// listDetachedRuns returns all recorded detached runs, newest first
+func listDetachedRuns() []*detachedRun {
+	entries, err := os.ReadDir(getDetachedRoot())
+	if err != nil {
+		return nil
+	}
+
+	var runs []*detachedRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := loadDetachedRun(entry.Name())
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+
+	return runs
+}
+
+// findDetachedRun resolves a full or prefix run ID to a single run
+func findDetachedRun(idPrefix string) (*detachedRun, error) {
+	var matches []*detachedRun
+	for _, run := range listDetachedRuns() {
+		if run.ID == idPrefix {
+			return run, nil
+		}
+		if strings.HasPrefix(run.ID, idPrefix) {
+			matches = append(matches, run)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("detached run not found: %s", idPrefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("run id %s is ambiguous (%d matches)", idPrefix, len(matches))
+	}
+}
+
+// parseRunCommandArgs separates the flags of ps, logs or kill from their positional arguments. These commands skip
+// urfave's flag parsing so that arguments meant for a recipe of the same name reach the recipe unchanged.
+func parseRunCommandArgs(c *cli.Context) (map[string]bool, []string, error) {
+	names := map[string]string{"help": "help", "h": "help"}
+	for _, flag := range c.Command.Flags {
+		for _, name := range flag.Names() {
+			names[name] = flag.Names()[0]
+		}
+	}
+
+	flags := make(map[string]bool)
+	var positionals []string
+	for _, arg := range c.Args().Slice() {
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positionals = append(positionals, arg)
+			continue
+		}
+		name, known := names[strings.TrimLeft(arg, "-")]
+		if !known {
+			return nil, nil, fmt.Errorf("flag provided but not defined: %s", arg)
+		}
+		flags[name] = true
+	}
+	return flags, positionals, nil
+}
+
+// dispatchShadowedRecipe runs the recipe named like the current command, such as the docker logs recipe, with the
+// command's arguments. It reports false when no recipe has that name.
+func dispatchShadowedRecipe(c *cli.Context) (bool, error) {
+	sourcePriority := getSourcePriority(c)
+	if _, found := findRecipeSummary(getRecipeCatalog().uniqueRecipes(sourcePriority, ""), c.Command.Name); !found {
+		return false, nil
+	}
+
+	Log(CategoryInit, fmt.Sprintf("No detached run matches, running recipe %s", c.Command.Name))
+	debugger := setupDebugging(c)
+	defer debugger()
+
+	return true, dispatch(c, append([]string{c.Command.Name}, c.Args().Slice()...), sourcePriority)
+}
+
+// detachedRunExists reports whether an id or id prefix refers to exactly one detached run
+func detachedRunExists(idPrefix string) bool {
+	_, err := findDetachedRun(idPrefix)
+	return err == nil
+}
+
+// handlePsCommand lists running and recent detached runs
+func handlePsCommand(c *cli.Context) error {
+	flags, args, err := parseRunCommandArgs(c)
+	if err != nil || len(args) > 0 {
+		if dispatched, dispatchErr := dispatchShadowedRecipe(c); dispatched {
+			return dispatchErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected argument: %s", args[0])
+	}
+	if flags["help"] {
+		return cli.ShowCommandHelp(c.Lineage()[1], c.Command.Name)
+	}
+
+	runs := listDetachedRuns()
+
+	if flags["json"] {
+		if runs == nil {
+			runs = []*detachedRun{}
+		}
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("No detached runs found.")
+		return nil
+	}
+
+	var rows [][]string
+	for _, run := range runs {
+		end := time.Now()
+		if run.FinishedAt != nil {
+			end = *run.FinishedAt
+		}
+		rows = append(rows, []string{
+			shortRunID(run.ID),
+			strings.Join(run.Args, " "),
+			run.Status,
+			strconv.Itoa(run.PID),
+			run.StartedAt.Format("2006-01-02 15:04:05"),
+			formatDuration(end.Sub(run.StartedAt)),
+		})
+	}
+
+	fmt.Println(renderSimpleTable([]string{"ID", "RECIPE", "STATUS", "PID", "STARTED", "DURATION"}, rows, "light"))
+	return nil
+}
+
+// shortRunID returns the abbreviated form of a run ID used for display
+func shortRunID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// handleLogsCommand prints the output of a detached run, optionally following it
+func handleLogsCommand(c *cli.Context) error {
+	flags, args, err := parseRunCommandArgs(c)
+	if err != nil || len(args) == 0 || !detachedRunExists(args[0]) {
+		if dispatched, dispatchErr := dispatchShadowedRecipe(c); dispatched {
+			return dispatchErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if flags["help"] {
+		return cli.ShowCommandHelp(c.Lineage()[1], c.Command.Name)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("you must specify a run id")
+	}
+
+	run, err := findDetachedRun(args[0])
+	if err != nil {
+		return err
+	}
+
+	_, _, outputPath := detachedRunPaths(run.ID)
+
+	done := make(chan struct{})
+	if !flags["follow"] || !isActiveRunStatus(run.Status) {
+		close(done)
+	} else {
+		go func() {
+			defer close(done)
+			for {
+				time.Sleep(500 * time.Millisecond)
+				current, err := loadDetachedRun(run.ID)
+				if err != nil || !isActiveRunStatus(current.Status) {
+					return
+				}
+			}
+		}()
+	}
+
+	return followLogFile(outputPath, done, os.Stdout)
+}
+
+// isActiveRunStatus reports whether a detached run may still produce output
+func isActiveRunStatus(status string) bool {
+	return status == RunRunning || status == RunStarting
+}
+
+// handleKillCommand terminates a detached run and all of its child processes
+func handleKillCommand(c *cli.Context) error {
+	flags, args, err := parseRunCommandArgs(c)
+	if err != nil || len(args) == 0 || !detachedRunExists(args[0]) {
+		if dispatched, dispatchErr := dispatchShadowedRecipe(c); dispatched {
+			return dispatchErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if flags["help"] {
+		return cli.ShowCommandHelp(c.Lineage()[1], c.Command.Name)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("you must specify a run id")
+	}
+
+	run, err := findDetachedRun(args[0])
+	if err != nil {
+		return err
+	}
+
+	if run.Status != RunRunning {
+		return fmt.Errorf("run %s is not running (status: %s)", shortRunID(run.ID), run.Status)
+	}
+
+	if err := killProcessTree(run.PID); err != nil {
+		return fmt.Errorf("failed to kill run %s: %w", shortRunID(run.ID), err)
+	}
+
+	_, resultPath, _ := detachedRunPaths(run.ID)
+	if err := writeJSONFile(resultPath, detachedRunResult{Status: RunKilled, FinishedAt: time.Now()}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Killed run %s\n", shortRunID(run.ID))
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package internal
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// detachedProcAttr starts the child in its own session so it survives the terminal closing
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processGroupProcAttr starts the child in its own process group so the group can be killed as a whole
+func processGroupProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group led by pid at once
+func killProcessGroup(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+// isProcessRunning reports whether a process with the given PID exists
+func isProcessRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// killProcessTree terminates the process group led by pid, escalating to SIGKILL after a grace period
+func killProcessTree(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && isProcessRunning(pid) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
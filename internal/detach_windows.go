@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// detachedProcessFlag is the Windows DETACHED_PROCESS creation flag
+const detachedProcessFlag = 0x00000008
+
+// Windows process access right and exit code used to check whether a process is still running
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActiveExitCode            = 259
+)
+
+// detachedProcAttr starts the child without a console in a new process group
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcessFlag}
+}
+
+// processGroupProcAttr starts the child in a new process group
+func processGroupProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessGroup kills the process and all of its descendants
+func killProcessGroup(pid int) error {
+	return killProcessTree(pid)
+}
+
+// isProcessRunning reports whether a process with the given PID is still running. A handle can be opened for a
+// process that has exited, so its exit code is checked as well.
+func isProcessRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActiveExitCode
+}
+
+// killProcessTree terminates the process and all of its descendants
+func killProcessTree(pid int) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
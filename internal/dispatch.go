@@ -34,6 +34,17 @@ func dispatch(c *cli.Context, args []string, sourcePriority []string) error {
 	input, vars := processRemainingArgs(remainingArgs)
 
 	for _, recipe := range recipes {
+		if c.Bool("strict") {
+			recipe.Strict = true
+		}
+		recipe.DryRun = c.Bool("dry-run")
+
+		if c.Bool("forget") {
+			if err := forgetPromptAnswers(recipe); err != nil {
+				return err
+			}
+		}
+
 		printDebugInfo(recipe, input, vars)
 
 		if err := evaluateRecipe(recipe, input, vars); err != nil {
@@ -44,9 +55,9 @@ func dispatch(c *cli.Context, args []string, sourcePriority []string) error {
 	return nil
 }
 
-// loadComponents discovers and loads components from all sources
+// loadComponents loads the components of every source. Only files that define components are parsed.
 func loadComponents(sourcePriority []string) {
-	sources := discoverComponentSources(sourcePriority)
+	sources := getRecipeCatalog().componentFiles(sourcePriority)
 
 	globalComponentRegistry.Clear()
 	if err := LoadComponents(sources); err != nil {
@@ -56,21 +67,6 @@ func loadComponents(sourcePriority []string) {
 	Log(CategoryComponent, fmt.Sprintf("Loaded %d components from all sources", len(globalComponentRegistry.components)))
 }
 
-// discoverComponentSources finds all files that might contain components
-func discoverComponentSources(sourcePriority []string) []string {
-	var allSources []string
-	for _, source := range sourcePriority {
-		useLocal := source == "local"
-		useUser := source == "user"
-		usePublic := source == "public"
-
-		sources, _ := findRecipeSourcesByType(useLocal, useUser, usePublic)
-		allSources = append(allSources, sources...)
-	}
-
-	return allSources
-}
-
 // loadRecipesToExecute determines which recipes to run based on provided arguments
 func loadRecipesToExecute(c *cli.Context, args []string, sourcePriority []string) ([]Recipe, []string, error) {
 	recipeFilePath := c.String("recipe-file")
@@ -205,7 +201,7 @@ func findRecipeInSources(recipeName, category string, sourcePriority []string, f
 	}
 
 	if fuzzyMatch {
-		allRecipes := collectAllUniqueRecipes(sourcePriority)
+		allRecipes := getRecipeCatalog().uniqueRecipes(sourcePriority, "")
 
 		if len(allRecipes) > 0 {
 			if match, found := fuzzyMatchRecipe(recipeName, extractRecipeNames(allRecipes), createRecipeMap(allRecipes)); found {
@@ -219,55 +215,27 @@ func findRecipeInSources(recipeName, category string, sourcePriority []string, f
 
 // searchSourceForRecipe searches for a recipe in a specific source
 func searchSourceForRecipe(source, recipeName, category string) (*Recipe, bool) {
-	useLocal := source == "local"
-	useUser := source == "user"
-	usePublic := source == "public"
+	recipes := getRecipeCatalog().sourceRecipes(source, category)
 
-	sources, _ := findRecipeSourcesByType(useLocal, useUser, usePublic)
-	recipes, _ := loadRecipes(sources, category)
-
-	recipe, err := findRecipeByName(recipes, recipeName)
-	if err == nil {
-		return recipe, true
-	}
-
-	if category != "" {
+	summary, found := findRecipeSummary(recipes, recipeName)
+	if !found && category != "" {
 		combinedName := fmt.Sprintf("%s-%s", category, recipeName)
-		recipe, err = findRecipeByName(recipes, combinedName)
-		if err == nil {
-			return recipe, true
-		}
+		summary, found = findRecipeSummary(recipes, combinedName)
 	}
-
-	return nil, false
-}
-
-// collectAllUniqueRecipes gathers unique recipes from all sources
-func collectAllUniqueRecipes(sourcePriority []string) []Recipe {
-	var allRecipes []Recipe
-	seenRecipeNames := make(map[string]bool)
-
-	for _, source := range sourcePriority {
-		useLocal := source == "local"
-		useUser := source == "user"
-		usePublic := source == "public"
-
-		sources, _ := findRecipeSourcesByType(useLocal, useUser, usePublic)
-		recipes, _ := loadRecipes(sources, "")
-
-		for _, recipe := range recipes {
-			if !seenRecipeNames[recipe.Name] {
-				allRecipes = append(allRecipes, recipe)
-				seenRecipeNames[recipe.Name] = true
-			}
-		}
+	if !found {
+		return nil, false
 	}
 
-	return allRecipes
+	recipe, err := summary.load()
+	if err != nil {
+		LogError("Failed to load recipe", err, nil)
+		return nil, false
+	}
+	return recipe, true
 }
 
 // extractRecipeNames gets all recipe names from a slice of recipes
-func extractRecipeNames(recipes []Recipe) []string {
+func extractRecipeNames(recipes []recipeSummary) []string {
 	names := make([]string, 0, len(recipes))
 	for _, recipe := range recipes {
 		names = append(names, recipe.Name)
@@ -276,8 +244,8 @@ func extractRecipeNames(recipes []Recipe) []string {
 }
 
 // createRecipeMap builds a map of recipe names to recipes
-func createRecipeMap(recipes []Recipe) map[string]Recipe {
-	recipeMap := make(map[string]Recipe)
+func createRecipeMap(recipes []recipeSummary) map[string]recipeSummary {
+	recipeMap := make(map[string]recipeSummary)
 	for _, recipe := range recipes {
 		recipeMap[recipe.Name] = recipe
 	}
@@ -296,7 +264,7 @@ func findRecipeByName(recipes []Recipe, name string) (*Recipe, error) {
 }
 
 // fuzzyMatchRecipe finds the closest recipe name and confirms with the user
-func fuzzyMatchRecipe(recipeName string, recipeNames []string, recipeMap map[string]Recipe) (*Recipe, bool) {
+func fuzzyMatchRecipe(recipeName string, recipeNames []string, recipeMap map[string]recipeSummary) (*Recipe, bool) {
 	if len(recipeNames) == 0 {
 		return nil, false
 	}
@@ -308,7 +276,12 @@ func fuzzyMatchRecipe(recipeName string, recipeNames []string, recipeMap map[str
 		recipe := recipeMap[bestMatch.name]
 
 		if confirmRecipeMatch(recipe) {
-			return &recipe, true
+			match, err := recipe.load()
+			if err != nil {
+				LogError("Failed to load recipe", err, nil)
+				return nil, false
+			}
+			return match, true
 		}
 	}
 
@@ -341,7 +314,7 @@ func findClosestRecipeMatches(recipeName string, recipeNames []string) []struct
 }
 
 // confirmRecipeMatch asks the user to confirm a fuzzy-matched recipe
-func confirmRecipeMatch(recipe Recipe) bool {
+func confirmRecipeMatch(recipe recipeSummary) bool {
 	var confirm bool
 	var promptMessage string
 
@@ -489,7 +462,7 @@ func handleCategorySelection(categoryName string, sourcePriority []string) (*Rec
 
 	for _, recipe := range recipes {
 		if recipe.Name == selected {
-			return &recipe, nil
+			return recipe.load()
 		}
 	}
 
@@ -497,31 +470,12 @@ func handleCategorySelection(categoryName string, sourcePriority []string) (*Rec
 }
 
 // collectRecipesInCategory gathers all recipes in a specific category
-func collectRecipesInCategory(categoryName string, sourcePriority []string) []Recipe {
-	var allRecipes []Recipe
-	recipeMap := make(map[string]Recipe)
-
-	for _, source := range sourcePriority {
-		useLocal := source == "local"
-		useUser := source == "user"
-		usePublic := source == "public"
-
-		sources, _ := findRecipeSourcesByType(useLocal, useUser, usePublic)
-		recipes, _ := loadRecipes(sources, categoryName)
-
-		for _, recipe := range recipes {
-			if _, exists := recipeMap[recipe.Name]; !exists {
-				recipeMap[recipe.Name] = recipe
-				allRecipes = append(allRecipes, recipe)
-			}
-		}
-	}
-
-	return allRecipes
+func collectRecipesInCategory(categoryName string, sourcePriority []string) []recipeSummary {
+	return getRecipeCatalog().uniqueRecipes(sourcePriority, categoryName)
 }
 
 // promptForRecipeSelection shows a selection dialog for recipes
-func promptForRecipeSelection(recipes []Recipe, categoryName string) (string, error) {
+func promptForRecipeSelection(recipes []recipeSummary, categoryName string) (string, error) {
 	options := make([]string, len(recipes)+1)
 	for i, recipe := range recipes {
 		options[i] = recipe.Name
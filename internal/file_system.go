@@ -12,21 +12,11 @@ import (
 
 // findRecipeSourceFile locates the file containing a specified recipe
 func findRecipeSourceFile(recipeName, category string, sourcePriority []string) (string, error) {
+	catalog := getRecipeCatalog()
 	for _, source := range sourcePriority {
-		useLocal := source == "local"
-		useUser := source == "user"
-		usePublic := source == "public"
-
-		sources, _ := findRecipeSourcesByType(useLocal, useUser, usePublic)
-
-		for _, sourceFile := range sources {
-			file, err := loadFile(sourceFile)
-			if err != nil {
-				continue
-			}
-
-			if foundRecipe := findRecipeInFile(file, recipeName, category); foundRecipe {
-				return sourceFile, nil
+		for _, recipe := range catalog.sourceRecipes(source, "") {
+			if isRecipeNamed(recipe, recipeName, category) {
+				return recipe.Path, nil
 			}
 		}
 	}
@@ -34,18 +24,16 @@ func findRecipeSourceFile(recipeName, category string, sourcePriority []string)
 	return "", fmt.Errorf("recipe not found: %s", recipeName)
 }
 
-// findRecipeInFile checks if a recipe exists in the given file
-func findRecipeInFile(file *File, recipeName, category string) bool {
-	for _, recipe := range file.Recipes {
-		if recipe.Name == recipeName {
-			return true
-		}
+// isRecipeNamed checks if a recipe has the given name, or the name prefixed with the category
+func isRecipeNamed(recipe recipeSummary, recipeName, category string) bool {
+	if recipe.Name == recipeName {
+		return true
+	}
 
-		if category != "" {
-			combinedName := fmt.Sprintf("%s-%s", category, recipeName)
-			if recipe.Name == combinedName {
-				return true
-			}
+	if category != "" {
+		combinedName := fmt.Sprintf("%s-%s", category, recipeName)
+		if recipe.Name == combinedName {
+			return true
 		}
 	}
 	return false
@@ -178,33 +166,6 @@ func loadFile(filename string) (*File, error) {
 	return &file, nil
 }
 
-// loadRecipes loads recipes from multiple sources with optional category filtering
-func loadRecipes(sources []string, category string) ([]Recipe, error) {
-	var allRecipes []Recipe
-	lowerCategory := strings.ToLower(category)
-
-	for _, source := range sources {
-		file, err := loadFile(source)
-		if err != nil {
-			fmt.Printf("Warning: Failed to load recipes from %s: %v\n", source, err)
-			continue
-		}
-
-		if category == "" {
-			allRecipes = append(allRecipes, file.Recipes...)
-			continue
-		}
-
-		for _, recipe := range file.Recipes {
-			if strings.ToLower(recipe.Category) == lowerCategory {
-				allRecipes = append(allRecipes, recipe)
-			}
-		}
-	}
-
-	return allRecipes, nil
-}
-
 // handleWhichCommand handles the 'which' command to show recipe file locations
 func handleWhichCommand(args []string, sourcePriority []string) error {
 	if len(args) == 0 {
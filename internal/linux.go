@@ -67,6 +67,19 @@ func getXDGDataHome() string {
 	return dataHome
 }
 
+// getXDGCacheHome returns the XDG_CACHE_HOME directory path
+func getXDGCacheHome() string {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(homeDir, ".cache")
+	}
+	return cacheHome
+}
+
 // isLinux determines if the current operating system is Linux
 func isLinux() bool {
 	return runtime.GOOS == "linux"
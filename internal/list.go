@@ -68,32 +68,20 @@ func determineSourceFlags(c *cli.Context) map[string]bool {
 }
 
 // collectRecipes gathers recipes from all specified sources
-func collectRecipes(sourcePriority []string, sourceFlags map[string]bool, category string) []Recipe {
-	var allRecipes []Recipe
-	recipeMap := make(map[string]bool)
-
+func collectRecipes(sourcePriority []string, sourceFlags map[string]bool, category string) []recipeSummary {
+	var sources []string
 	for _, source := range sourcePriority {
-		if !sourceFlags[source] {
-			continue
-		}
-
-		sources, _ := findRecipeSourcesByType(source == "local", source == "user", source == "public")
-		recipes, _ := loadRecipes(sources, category)
-
-		for _, r := range recipes {
-			if !recipeMap[r.Name] {
-				allRecipes = append(allRecipes, r)
-				recipeMap[r.Name] = true
-			}
+		if sourceFlags[source] {
+			sources = append(sources, source)
 		}
 	}
 
-	return allRecipes
+	return getRecipeCatalog().uniqueRecipes(sources, category)
 }
 
 // filterDemoRecipes removes recipes with the "demo" category
-func filterDemoRecipes(recipes []Recipe) []Recipe {
-	var filtered []Recipe
+func filterDemoRecipes(recipes []recipeSummary) []recipeSummary {
+	var filtered []recipeSummary
 	for _, recipe := range recipes {
 		if recipe.Category != "demo" {
 			filtered = append(filtered, recipe)
@@ -113,7 +101,7 @@ func handleEmptyResults(c *cli.Context) error {
 }
 
 // listRecipes displays recipes grouped by category in a formatted text output
-func listRecipes(recipes []Recipe) {
+func listRecipes(recipes []recipeSummary) {
 	if len(recipes) == 0 {
 		fmt.Println(FormatText("No recipes found.", ColorYellow, StyleNone))
 		return
@@ -133,8 +121,8 @@ func listRecipes(recipes []Recipe) {
 }
 
 // groupRecipesByCategory organizes recipes into a map keyed by category
-func groupRecipesByCategory(recipes []Recipe) map[string][]Recipe {
-	categories := make(map[string][]Recipe)
+func groupRecipesByCategory(recipes []recipeSummary) map[string][]recipeSummary {
+	categories := make(map[string][]recipeSummary)
 	for _, recipe := range recipes {
 		cat := recipe.Category
 		if cat == "" {
@@ -146,7 +134,7 @@ func groupRecipesByCategory(recipes []Recipe) map[string][]Recipe {
 }
 
 // getSortedCategoryNames returns category names in alphabetical order
-func getSortedCategoryNames(categories map[string][]Recipe) []string {
+func getSortedCategoryNames(categories map[string][]recipeSummary) []string {
 	var names []string
 	for category := range categories {
 		names = append(names, category)
@@ -166,7 +154,7 @@ func printCategoryHeader(category string) {
 }
 
 // printCategoryRecipes displays all recipes within a category
-func printCategoryRecipes(recipes []Recipe) {
+func printCategoryRecipes(recipes []recipeSummary) {
 	sort.Slice(recipes, func(i, j int) bool {
 		return recipes[i].Name < recipes[j].Name
 	})
@@ -182,7 +170,7 @@ func printCategoryRecipes(recipes []Recipe) {
 }
 
 // outputRecipesAsJSON formats and outputs recipes as JSON
-func outputRecipesAsJSON(recipes []Recipe) error {
+func outputRecipesAsJSON(recipes []recipeSummary) error {
 	result := make([]recipeInfo, len(recipes))
 	for i, r := range recipes {
 		result[i] = recipeInfo{
@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// formFieldTypes are the prompt types a form field can use
+var formFieldTypes = map[string]bool{
+	"input":        true,
+	"password":     true,
+	"number":       true,
+	"confirm":      true,
+	"select":       true,
+	"autocomplete": true,
+	"multiselect":  true,
+	"path":         true,
+	"date":         true,
+	"datetime":     true,
+	"duration":     true,
+	"list":         true,
+	"keyvalue":     true,
+}
+
+// formSubmit is the review option that submits a form
+const formSubmit = "Submit"
+
+// handleFormPrompt asks the fields of a form in order and then shows every answer on one review screen, where fields
+// can be changed until the form's checks pass. Fields answered on the command line are not asked, and when no field
+// needed asking the checks are applied without a review. Each field is stored as its own variable as it is answered,
+// so field conditions and checks can refer to other fields.
+func handleFormPrompt(p Prompt, ctx *ExecutionContext) (interface{}, error) {
+	formID := promptVarName(p)
+	if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("form prompt %s has no fields", formID)
+	}
+	for _, field := range p.Fields {
+		if !formFieldTypes[field.Type] {
+			return nil, fmt.Errorf("form prompt %s: field %s has unsupported type %s", formID, promptVarName(field), field.Type)
+		}
+	}
+
+	values := make(map[string]interface{}, len(p.Fields))
+	asked := false
+	for _, field := range p.Fields {
+		if !shouldAskPrompt(field, ctx) {
+			continue
+		}
+
+		_, fromFlag := ctx.promptAnswer(promptVarName(field))
+		value, err := answerPrompt(field, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if isExitAnswer(field, value) {
+			return ExitPrompt, nil
+		}
+
+		asked = asked || !fromFlag
+		storeFormField(field, value, values, ctx)
+	}
+
+	if !asked {
+		if failed := failedFormChecks(p, ctx); len(failed) > 0 {
+			return nil, fmt.Errorf("form '%s': %s", formID, strings.Join(failed, "; "))
+		}
+		return values, nil
+	}
+
+	return reviewForm(p, values, ctx)
+}
+
+// reviewForm shows the form's answers until the user submits a form that passes its checks
+func reviewForm(p Prompt, values map[string]interface{}, ctx *ExecutionContext) (interface{}, error) {
+	message, err := renderTemplate(p.Message, ctx.templateVars())
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	for {
+		var fields []Prompt
+		for _, field := range p.Fields {
+			if _, ok := values[promptVarName(field)]; ok {
+				fields = append(fields, field)
+			}
+		}
+
+		options := make([]string, 0, len(fields)+1)
+		for _, field := range fields {
+			options = append(options, fmt.Sprintf("%s: %s", field.Name, formatFormValue(field, values[promptVarName(field)])))
+		}
+		options = append(options, formSubmit)
+
+		reviewMessage := message
+		if len(failed) > 0 {
+			reviewMessage += "\n  ✗ " + strings.Join(failed, "\n  ✗ ")
+		}
+
+		var choice int
+		prompt := &survey.Select{
+			Message:  reviewMessage,
+			Options:  options,
+			Default:  len(options) - 1,
+			Help:     "Select a field to change its answer, or submit the form",
+			PageSize: len(options),
+		}
+		if err := survey.AskOne(prompt, &choice); err != nil {
+			return nil, err
+		}
+
+		if choice == len(fields) {
+			if failed = failedFormChecks(p, ctx); len(failed) == 0 {
+				return values, nil
+			}
+			continue
+		}
+
+		field := fields[choice]
+		if field.Type != "password" && !field.Secret {
+			field.Default = formatPromptDefault(values[promptVarName(field)])
+		}
+		value, err := handlePrompt(field, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if isExitAnswer(field, value) {
+			return ExitPrompt, nil
+		}
+		storeFormField(field, value, values, ctx)
+
+		exited, err := syncFormFields(p, values, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if exited {
+			return ExitPrompt, nil
+		}
+		failed = nil
+	}
+}
+
+// syncFormFields asks fields whose condition has become true after an answer changed, and clears fields whose
+// condition no longer holds. It reports whether the user chose to exit from one of the fields it asked.
+func syncFormFields(p Prompt, values map[string]interface{}, ctx *ExecutionContext) (bool, error) {
+	for _, field := range p.Fields {
+		fieldID := promptVarName(field)
+		_, answered := values[fieldID]
+		active := shouldAskPrompt(field, ctx)
+
+		switch {
+		case active && !answered:
+			value, err := handlePrompt(field, ctx)
+			if err != nil {
+				return false, err
+			}
+			if isExitAnswer(field, value) {
+				return true, nil
+			}
+			storeFormField(field, value, values, ctx)
+		case !active && answered:
+			delete(values, fieldID)
+			ctx.clearPromptAnswer(fieldID)
+		}
+	}
+	return false, nil
+}
+
+// storeFormField records a field's answer in the form and as its own variable
+func storeFormField(field Prompt, value interface{}, values map[string]interface{}, ctx *ExecutionContext) {
+	fieldID := promptVarName(field)
+	values[fieldID] = value
+
+	if !ctx.DryRun {
+		ctx.promptMemory.remember(field, value)
+	}
+	ctx.storePromptAnswer(fieldID, value)
+}
+
+// failedFormChecks returns the messages of the form checks that do not hold
+func failedFormChecks(p Prompt, ctx *ExecutionContext) []string {
+	var failed []string
+	for _, check := range p.Checks {
+		ok, err := evaluateCondition(check.Condition, ctx)
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("invalid check %q: %v", check.Condition, err))
+		case !ok && check.Message != "":
+			failed = append(failed, check.Message)
+		case !ok:
+			failed = append(failed, fmt.Sprintf("check failed: %s", check.Condition))
+		}
+	}
+	return failed
+}
+
+// formatFormValue formats a field's answer for the review screen, hiding passwords and secrets
+func formatFormValue(field Prompt, value interface{}) string {
+	if field.Type == "password" || field.Secret {
+		return "********"
+	}
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, ", ")
+	case map[string]interface{}:
+		return strings.ReplaceAll(formatPromptDefault(v), ",", ", ")
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// formatPromptDefault formats an answer so it can be offered as a prompt's default
+func formatPromptDefault(value interface{}) string {
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = fmt.Sprintf("%s=%v", key, v[key])
+		}
+		return strings.Join(pairs, ",")
+	}
+	return fmt.Sprintf("%v", value)
+}
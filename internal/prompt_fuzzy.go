@@ -0,0 +1,471 @@
+package internal
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// Fuzzy match scoring, loosely following fzf: every matched rune scores, runs of consecutive matches and matches at
+// the start of a word score more, and gaps between matches cost a little
+const (
+	fuzzyScoreMatch          = 16
+	fuzzyBonusBoundary       = 8
+	fuzzyBonusConsecutive    = 8
+	fuzzyPenaltyGapStart     = 3
+	fuzzyPenaltyGapExtension = 1
+	fuzzyDescriptionDivisor  = 2
+)
+
+// fuzzyPageSize is the number of matches shown at once
+const fuzzyPageSize = 10
+
+// fuzzyResult is an option that matched the current query
+type fuzzyResult struct {
+	Index                int
+	Value                string
+	Description          string
+	Score                int
+	ValuePositions       []int
+	DescriptionPositions []int
+}
+
+// fuzzySegment is a piece of option text, marked when it matched the query
+type fuzzySegment struct {
+	Text  string
+	Match bool
+}
+
+// fuzzyRow is a rendered option line
+type fuzzyRow struct {
+	Value       []fuzzySegment
+	Description []fuzzySegment
+	Focused     bool
+	Selected    bool
+}
+
+// FuzzySelect is a survey prompt that filters a large option list with ranked fuzzy matching, similar to fzf.
+// Each space-separated term of the query must match the option text or its description.
+type FuzzySelect struct {
+	survey.Renderer
+	Message      string
+	Options      []string
+	Descriptions map[string]string
+	Default      []string
+	Help         string
+	Multi        bool
+	Limit        int
+	PageSize     int
+
+	query       []rune
+	results     []fuzzyResult
+	focus       int
+	selected    map[int]bool
+	showingHelp bool
+}
+
+// fuzzySelectTemplateData is passed to FuzzySelectTemplate
+type fuzzySelectTemplateData struct {
+	Message    string
+	Query      string
+	Help       string
+	Multi      bool
+	Matched    int
+	Total      int
+	Rows       []fuzzyRow
+	ShowHelp   bool
+	ShowAnswer bool
+	Answer     string
+	Config     *survey.PromptConfig
+}
+
+// FuzzySelectTemplate renders the fuzzy prompt with survey's icons and colors
+var FuzzySelectTemplate = `
+{{- define "segments"}}{{ range . }}{{ if .Match }}{{ color "green+hb" }}{{ .Text }}{{ color "reset" }}{{ else }}{{ .Text }}{{ end }}{{ end }}{{ end }}
+{{- if .ShowHelp }}{{- color .Config.Icons.Help.Format }}{{ .Config.Icons.Help.Text }} {{ .Help }}{{color "reset"}}{{"\n"}}{{end}}
+{{- color .Config.Icons.Question.Format }}{{ .Config.Icons.Question.Text }} {{color "reset"}}
+{{- color "default+hb"}}{{ .Message }}{{color "reset"}}
+{{- if .ShowAnswer}}{{color "cyan"}} {{.Answer}}{{color "reset"}}{{"\n"}}
+{{- else}}
+  {{- " "}}{{ color "cyan" }}>{{ color "reset" }} {{ .Query }}{{"\n"}}
+  {{- color "cyan"}}  {{ .Matched }}/{{ .Total }} [Type to filter, arrows to move{{ if .Multi }}, tab to toggle{{ end }}, enter to select
+  {{- if and .Help (not .ShowHelp)}}, {{ .Config.HelpInput }} for more help{{end}}]{{color "reset"}}{{"\n"}}
+  {{- range .Rows }}
+    {{- if .Focused }}{{ color $.Config.Icons.SelectFocus.Format }}{{ $.Config.Icons.SelectFocus.Text }} {{ color "reset" }}{{ else }}  {{ end }}
+    {{- if $.Multi }}{{ if .Selected }}{{ color $.Config.Icons.MarkedOption.Format }}{{ $.Config.Icons.MarkedOption.Text }}{{ else }}{{ color $.Config.Icons.UnmarkedOption.Format }}{{ $.Config.Icons.UnmarkedOption.Text }}{{ end }}{{ color "reset" }} {{ end }}
+    {{- template "segments" .Value }}
+    {{- if .Description }} - {{ color "cyan" }}{{ template "segments" .Description }}{{ color "reset" }}{{ end }}{{"\n"}}
+  {{- end }}
+{{- end}}`
+
+// handleFuzzyPrompt displays a fuzzy-finder selection prompt
+func handleFuzzyPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string, validator survey.Validator) (interface{}, error) {
+	options, descriptions, err := getPromptOptions(p, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := &FuzzySelect{
+		Message:      message,
+		Options:      options,
+		Descriptions: descriptions,
+		Help:         helpText,
+		Multi:        p.Multiple,
+		Limit:        p.MultipleLimit,
+		PageSize:     fuzzyPageSize,
+	}
+
+	if p.Multiple {
+		prompt.Default = parseDefaultOptions(defaultValue, options)
+
+		var answer []string
+		if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}
+
+	if defaultValue != "" {
+		prompt.Default = []string{getDefaultOption(defaultValue, options)}
+	}
+
+	var answer string
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
+		return "", err
+	}
+	return answer, nil
+}
+
+// Prompt shows the fuzzy finder and returns a core.OptionAnswer, or a []core.OptionAnswer in multi mode
+func (f *FuzzySelect) Prompt(config *survey.PromptConfig) (interface{}, error) {
+	if len(f.Options) == 0 {
+		return nil, errors.New("please provide options to select from")
+	}
+
+	f.reset()
+
+	cursor := f.NewCursor()
+	cursor.Hide()
+	defer cursor.Show()
+
+	if err := f.Render(FuzzySelectTemplate, f.templateData(config)); err != nil {
+		return nil, err
+	}
+
+	rr := f.NewRuneReader()
+	_ = rr.SetTermMode()
+	defer func() {
+		_ = rr.RestoreTermMode()
+	}()
+
+	for {
+		r, _, err := rr.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+		if r == terminal.KeyInterrupt {
+			return nil, terminal.InterruptErr
+		}
+		if r == terminal.KeyEndTransmission || f.handleKey(r, config.HelpInput) {
+			break
+		}
+		_ = f.Render(FuzzySelectTemplate, f.templateData(config))
+	}
+
+	return f.answer(), nil
+}
+
+// Cleanup renders the prompt with the chosen answer
+func (f *FuzzySelect) Cleanup(config *survey.PromptConfig, val interface{}) error {
+	values, _ := promptAnswerValues(val)
+
+	data := f.templateData(config)
+	data.ShowAnswer = true
+	data.Answer = strings.Join(values, ", ")
+	return f.Render(FuzzySelectTemplate, data)
+}
+
+// reset clears the query and applies the default selection
+func (f *FuzzySelect) reset() {
+	f.query = nil
+	f.focus = 0
+	f.selected = make(map[int]bool)
+	f.results = rankFuzzyOptions("", f.Options, f.Descriptions)
+
+	for _, def := range f.Default {
+		for i, opt := range f.Options {
+			if opt != def {
+				continue
+			}
+			if f.Multi {
+				f.selected[i] = true
+			} else {
+				f.focus = i
+			}
+		}
+	}
+}
+
+// handleKey updates the prompt for a key press and reports whether the answer was submitted
+func (f *FuzzySelect) handleKey(key rune, helpInput string) bool {
+	oldQuery := string(f.query)
+
+	switch {
+	case key == terminal.KeyEnter || key == '\n':
+		if len(f.results) == 0 {
+			return false
+		}
+		if f.Multi && len(f.selected) == 0 {
+			f.selected[f.results[f.focus].Index] = true
+		}
+		return true
+	case key == terminal.KeyArrowUp:
+		f.moveFocus(-1)
+	case key == terminal.KeyArrowDown:
+		f.moveFocus(1)
+	case key == terminal.KeyTab:
+		if f.Multi && len(f.results) > 0 {
+			f.toggle(f.results[f.focus].Index)
+		}
+		f.moveFocus(1)
+	case string(key) == helpInput && f.Help != "" && len(f.query) == 0:
+		f.showingHelp = true
+	case key == terminal.KeyDeleteWord || key == terminal.KeyDeleteLine || key == terminal.KeyEscape:
+		f.query = nil
+	case key == terminal.KeyDelete || key == terminal.KeyBackspace:
+		if len(f.query) > 0 {
+			f.query = f.query[:len(f.query)-1]
+		}
+	case key >= terminal.KeySpace:
+		f.query = append(f.query, key)
+	}
+
+	if string(f.query) != oldQuery {
+		f.results = rankFuzzyOptions(string(f.query), f.Options, f.Descriptions)
+		f.focus = 0
+	}
+	return false
+}
+
+// moveFocus moves the focused match up or down, wrapping around the ends
+func (f *FuzzySelect) moveFocus(delta int) {
+	if len(f.results) == 0 {
+		return
+	}
+	f.focus = (f.focus + delta + len(f.results)) % len(f.results)
+}
+
+// toggle marks or unmarks an option, respecting the selection limit
+func (f *FuzzySelect) toggle(index int) {
+	if f.selected[index] {
+		delete(f.selected, index)
+		return
+	}
+	if f.Limit > 0 && len(f.selected) >= f.Limit {
+		return
+	}
+	f.selected[index] = true
+}
+
+// answer returns the focused option, or the marked options in their original order in multi mode
+func (f *FuzzySelect) answer() interface{} {
+	if !f.Multi {
+		if len(f.results) == 0 {
+			return core.OptionAnswer{}
+		}
+		result := f.results[f.focus]
+		return core.OptionAnswer{Value: result.Value, Index: result.Index}
+	}
+
+	answers := []core.OptionAnswer{}
+	for i, opt := range f.Options {
+		if f.selected[i] {
+			answers = append(answers, core.OptionAnswer{Value: opt, Index: i})
+		}
+	}
+	return answers
+}
+
+// templateData builds the visible page of matches around the focused one
+func (f *FuzzySelect) templateData(config *survey.PromptConfig) fuzzySelectTemplateData {
+	pageSize := f.PageSize
+	if pageSize <= 0 {
+		pageSize = config.PageSize
+	}
+
+	start := 0
+	if f.focus >= pageSize {
+		start = f.focus - pageSize + 1
+	}
+	end := start + pageSize
+	if end > len(f.results) {
+		end = len(f.results)
+	}
+
+	rows := make([]fuzzyRow, 0, end-start)
+	for i := start; i < end; i++ {
+		result := f.results[i]
+		rows = append(rows, fuzzyRow{
+			Value:       fuzzySegments(result.Value, result.ValuePositions),
+			Description: fuzzySegments(result.Description, result.DescriptionPositions),
+			Focused:     i == f.focus,
+			Selected:    f.selected[result.Index],
+		})
+	}
+
+	return fuzzySelectTemplateData{
+		Message:  f.Message,
+		Query:    string(f.query),
+		Help:     f.Help,
+		Multi:    f.Multi,
+		Matched:  len(f.results),
+		Total:    len(f.Options),
+		Rows:     rows,
+		ShowHelp: f.showingHelp,
+		Config:   config,
+	}
+}
+
+// rankFuzzyOptions returns the options matching every term of the query, best matches first. Ties keep the shorter
+// option, then the original order. An empty query returns every option in its original order.
+func rankFuzzyOptions(query string, options []string, descriptions map[string]string) []fuzzyResult {
+	terms := strings.Fields(query)
+	results := make([]fuzzyResult, 0, len(options))
+
+	for i, opt := range options {
+		result := fuzzyResult{Index: i, Value: opt, Description: descriptions[opt]}
+		matched := true
+
+		for _, term := range terms {
+			score, positions, ok := fuzzyMatch(term, opt)
+			if ok {
+				result.Score += score
+				result.ValuePositions = append(result.ValuePositions, positions...)
+				continue
+			}
+
+			score, positions, ok = fuzzyMatch(term, result.Description)
+			if !ok {
+				matched = false
+				break
+			}
+			result.Score += score / fuzzyDescriptionDivisor
+			result.DescriptionPositions = append(result.DescriptionPositions, positions...)
+		}
+
+		if matched {
+			results = append(results, result)
+		}
+	}
+
+	if len(terms) > 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			if results[i].Score != results[j].Score {
+				return results[i].Score > results[j].Score
+			}
+			return len(results[i].Value) < len(results[j].Value)
+		})
+	}
+
+	return results
+}
+
+// fuzzyMatch finds the runes of a term in order within text. Matching ignores case unless the term contains an
+// upper case letter. After the first match is found the window is narrowed from the end, so "abc" in "a_abc" scores
+// the tight run rather than the scattered one.
+func fuzzyMatch(term, text string) (int, []int, bool) {
+	if term == "" {
+		return 0, nil, true
+	}
+
+	pattern := []rune(term)
+	runes := []rune(text)
+	caseSensitive := strings.IndexFunc(term, unicode.IsUpper) >= 0
+	equal := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// forward scan for the end of the first complete match
+	pi, end := 0, -1
+	for i, r := range runes {
+		if equal(r, pattern[pi]) {
+			pi++
+			if pi == len(pattern) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// backward scan from the end for the shortest window
+	positions := make([]int, len(pattern))
+	pi = len(pattern) - 1
+	for i := end; i >= 0 && pi >= 0; i-- {
+		if equal(runes[i], pattern[pi]) {
+			positions[pi] = i
+			pi--
+		}
+	}
+
+	score := 0
+	for k, pos := range positions {
+		score += fuzzyScoreMatch
+		if isFuzzyBoundary(runes, pos) {
+			score += fuzzyBonusBoundary
+		}
+		if k > 0 {
+			if gap := pos - positions[k-1] - 1; gap == 0 {
+				score += fuzzyBonusConsecutive
+			} else {
+				score -= fuzzyPenaltyGapStart + (gap-1)*fuzzyPenaltyGapExtension
+			}
+		}
+	}
+
+	return score, positions, true
+}
+
+// isFuzzyBoundary reports whether a rune starts a word: the start of the text, after a separator or a camelCase hump
+func isFuzzyBoundary(runes []rune, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	prev, cur := runes[pos-1], runes[pos]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// fuzzySegments splits text into matched and unmatched runs for highlighting
+func fuzzySegments(text string, positions []int) []fuzzySegment {
+	if text == "" {
+		return nil
+	}
+
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+
+	var segments []fuzzySegment
+	for i, r := range []rune(text) {
+		if n := len(segments); n > 0 && segments[n-1].Match == matched[i] {
+			segments[n-1].Text += string(r)
+			continue
+		}
+		segments = append(segments, fuzzySegment{Text: string(r), Match: matched[i]})
+	}
+	return segments
+}
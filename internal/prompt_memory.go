@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// promptMemory holds the remembered prompt answers of the running recipe, keyed by prompt ID
+type promptMemory struct {
+	mu      sync.Mutex
+	path    string
+	enabled bool
+	loaded  bool
+	answers map[string]string
+}
+
+// getPromptMemoryRoot returns the directory holding remembered prompt answers
+func getPromptMemoryRoot() string {
+	return filepath.Join(getXDGDataHome(), "shef", "answers")
+}
+
+// promptMemoryPath returns the file holding the remembered answers of a recipe
+func promptMemoryPath(recipe Recipe) string {
+	name := recipe.Name
+	if recipe.Category != "" {
+		name = recipe.Category + "." + recipe.Name
+	}
+	return filepath.Join(getPromptMemoryRoot(), sanitizeFileName(name)+".json")
+}
+
+// newPromptMemory returns the prompt memory of a recipe. Answers are loaded on first use.
+func newPromptMemory(recipe Recipe) *promptMemory {
+	return &promptMemory{
+		path:    promptMemoryPath(recipe),
+		enabled: recipe.RememberPrompts,
+	}
+}
+
+// forgetPromptAnswers removes the remembered answers of a recipe
+func forgetPromptAnswers(recipe Recipe) error {
+	path := promptMemoryPath(recipe)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to forget prompt answers for %s: %w", recipe.Name, err)
+	}
+	Log(CategoryFileSystem, fmt.Sprintf("Forgot prompt answers: %s", path))
+	return nil
+}
+
+// promptVarName returns the variable a prompt's answer is stored in
+func promptVarName(p Prompt) string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.Name
+}
+
+// shouldRemember reports whether a prompt's answer may be stored. Password and secret prompts never are.
+func (m *promptMemory) shouldRemember(p Prompt) bool {
+	if m == nil || p.Type == "password" || p.Secret {
+		return false
+	}
+	if p.Remember != nil {
+		return *p.Remember
+	}
+	return m.enabled
+}
+
+// recall returns the remembered answer of a prompt, formatted as a prompt default
+func (m *promptMemory) recall(p Prompt) (string, bool) {
+	if !m.shouldRemember(p) {
+		return "", false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	answer, ok := m.answers[promptVarName(p)]
+	return answer, ok
+}
+
+// remember stores a prompt's answer so it is offered as the default next time
+func (m *promptMemory) remember(p Prompt, value interface{}) {
+	if !m.shouldRemember(p) {
+		return
+	}
+	if _, isMap := value.(map[string]interface{}); isMap && p.Type != "keyvalue" {
+		// whole table rows and forms are not stored; a table_select prompt with a value_field is remembered by that value
+		return
+	}
+
+	answer := formatPromptDefault(value)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	if existing, ok := m.answers[promptVarName(p)]; ok && existing == answer {
+		return
+	}
+	m.answers[promptVarName(p)] = answer
+
+	if err := m.save(); err != nil {
+		LogError("Failed to remember prompt answer", err, map[string]interface{}{"prompt": promptVarName(p)})
+	}
+}
+
+// load reads the remembered answers from disk once. A missing or unreadable file starts an empty memory.
+func (m *promptMemory) load() {
+	if m.loaded {
+		return
+	}
+	m.loaded = true
+	m.answers = make(map[string]string)
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &m.answers); err != nil {
+		LogError("Ignoring unreadable prompt answers", err, map[string]interface{}{"path": m.path})
+		m.answers = make(map[string]string)
+	}
+}
+
+// save writes the remembered answers to disk, replacing the file atomically
+func (m *promptMemory) save() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(m.path), err)
+	}
+
+	data, err := json.MarshalIndent(m.answers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, m.path)
+}
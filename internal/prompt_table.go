@@ -0,0 +1,432 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// maxTableCellWidth truncates long cell values so every row stays on one line
+const maxTableCellWidth = 48
+
+// tableSelectLine is a rendered table row
+type tableSelectLine struct {
+	Text    string
+	Focused bool
+}
+
+// TableSelect is a survey prompt that shows rows as a table and lets the user pick one. Typing filters the rows by
+// substring across all of their cells.
+type TableSelect struct {
+	survey.Renderer
+	Message  string
+	Help     string
+	Columns  []string
+	Rows     []map[string]interface{}
+	Keys     []string
+	Style    table.Style
+	Default  string
+	PageSize int
+
+	filter      []rune
+	matches     []int
+	focus       int
+	header      []string
+	rowLines    []string
+	footer      []string
+	showingHelp bool
+}
+
+// tableSelectTemplateData is passed to TableSelectTemplate
+type tableSelectTemplateData struct {
+	Message     string
+	Filter      string
+	Help        string
+	Matched     int
+	Total       int
+	Header      []string
+	Rows        []tableSelectLine
+	Footer      []string
+	ExitFocused bool
+	ShowHelp    bool
+	ShowAnswer  bool
+	Answer      string
+	Config      *survey.PromptConfig
+}
+
+// TableSelectTemplate renders the table prompt with survey's icons and colors
+var TableSelectTemplate = `
+{{- if .ShowHelp }}{{- color .Config.Icons.Help.Format }}{{ .Config.Icons.Help.Text }} {{ .Help }}{{color "reset"}}{{"\n"}}{{end}}
+{{- color .Config.Icons.Question.Format }}{{ .Config.Icons.Question.Text }} {{color "reset"}}
+{{- color "default+hb"}}{{ .Message }}{{ if .Filter }} {{ .Filter }}{{ end }}{{color "reset"}}
+{{- if .ShowAnswer}}{{color "cyan"}} {{.Answer}}{{color "reset"}}{{"\n"}}
+{{- else}}
+  {{- "  "}}{{- color "cyan"}}{{ .Matched }}/{{ .Total }} [Use arrows to move, type to filter
+  {{- if and .Help (not .ShowHelp)}}, {{ .Config.HelpInput }} for more help{{end}}]{{color "reset"}}{{"\n"}}
+  {{- range .Header }}  {{ . }}{{"\n"}}{{ end }}
+  {{- range .Rows }}
+    {{- if .Focused }}{{ color $.Config.Icons.SelectFocus.Format }}{{ $.Config.Icons.SelectFocus.Text }} {{ color "cyan+b" }}{{ .Text }}{{ color "reset" }}{{ else }}  {{ .Text }}{{ end }}{{"\n"}}
+  {{- end }}
+  {{- range .Footer }}  {{ . }}{{"\n"}}{{ end }}
+  {{- if .ExitFocused }}{{ color .Config.Icons.SelectFocus.Format }}{{ .Config.Icons.SelectFocus.Text }} {{ color "cyan+b" }}Exit{{ color "reset" }}{{ else }}  Exit{{ end }}{{"\n"}}
+{{- end}}`
+
+// handleTableSelectPrompt displays the rows of a JSON array as a table and returns the picked row, or the row's
+// value_field when one is set
+func handleTableSelectPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string, validator survey.Validator) (interface{}, error) {
+	rows, columns, err := getTableRows(p, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := tableRowKeys(p, rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := &TableSelect{
+		Message:  message,
+		Help:     helpText,
+		Columns:  columns,
+		Rows:     rows,
+		Keys:     keys,
+		Style:    tableStyleByName(p.TableStyle),
+		Default:  defaultValue,
+		PageSize: 10,
+	}
+
+	var answer core.OptionAnswer
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
+		return nil, err
+	}
+	if answer.Value == ExitPrompt {
+		return ExitPrompt, nil
+	}
+
+	return tableRowValue(p, rows[answer.Index]), nil
+}
+
+// tableSelectAnswerFromFlag picks the row whose key matches a value passed on the command line
+func tableSelectAnswerFromFlag(p Prompt, str string, ctx *ExecutionContext) (interface{}, error) {
+	rows, columns, err := getTableRows(p, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := tableRowKeys(p, rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		if key == str {
+			return tableRowValue(p, rows[i]), nil
+		}
+	}
+	return nil, checkPromptOption(str, keys)
+}
+
+// getTableRows parses the source operation output as a JSON array of objects. Columns are the prompt's columns, or
+// every field in the order it first appears.
+func getTableRows(p Prompt, ctx *ExecutionContext) ([]map[string]interface{}, []string, error) {
+	if p.SourceOp == "" {
+		return nil, nil, fmt.Errorf("table_select prompt %s requires a source_operation", p.Name)
+	}
+
+	output, sourceOp, err := sourceOperationOutput(p, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rawRows []json.RawMessage
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &rawRows); err != nil {
+		return nil, nil, fmt.Errorf("output of source operation %s is not a JSON array: %w", sourceOp, err)
+	}
+	if len(rawRows) == 0 {
+		return nil, nil, fmt.Errorf("no rows found from source operation %s", sourceOp)
+	}
+
+	rows := make([]map[string]interface{}, 0, len(rawRows))
+	columns := p.Columns
+	seen := make(map[string]bool)
+
+	for i, raw := range rawRows {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err != nil || row == nil {
+			return nil, nil, fmt.Errorf("row %d of source operation %s is not a JSON object", i+1, sourceOp)
+		}
+		rows = append(rows, normalizeJSONNumbers(row).(map[string]interface{}))
+
+		if len(p.Columns) == 0 {
+			for _, key := range jsonObjectKeys(raw) {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+	}
+
+	return rows, columns, nil
+}
+
+// jsonObjectKeys returns the keys of a JSON object in document order
+func jsonObjectKeys(raw json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		key, _ := tok.(string)
+		keys = append(keys, key)
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+// tableRowKeys returns the value identifying each row: its value_field, or its first column
+func tableRowKeys(p Prompt, rows []map[string]interface{}, columns []string) ([]string, error) {
+	field := p.ValueField
+	if field == "" && len(columns) > 0 {
+		field = columns[0]
+	}
+
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		value, ok := row[field]
+		if !ok {
+			return nil, fmt.Errorf("row %d has no %s field", i+1, field)
+		}
+		keys[i] = formatTableCell(value)
+	}
+	return keys, nil
+}
+
+// tableRowValue returns what a picked row stores: the value_field when set, otherwise the whole object
+func tableRowValue(p Prompt, row map[string]interface{}) interface{} {
+	if p.ValueField != "" {
+		return row[p.ValueField]
+	}
+	return row
+}
+
+// formatTableCell formats a JSON value for display, keeping whole numbers free of exponents
+func formatTableCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// truncateTableCell shortens a cell to one line of at most maxTableCellWidth runes
+func truncateTableCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= maxTableCellWidth {
+		return s
+	}
+	return string([]rune(s)[:maxTableCellWidth-1]) + "…"
+}
+
+// Prompt shows the table and returns the picked row as a core.OptionAnswer whose Index is the row index
+func (t *TableSelect) Prompt(config *survey.PromptConfig) (interface{}, error) {
+	if len(t.Rows) == 0 {
+		return nil, errors.New("please provide rows to select from")
+	}
+
+	t.filter = nil
+	t.refresh()
+	for i, match := range t.matches {
+		if match >= 0 && t.Keys[match] == t.Default {
+			t.focus = i
+		}
+	}
+
+	cursor := t.NewCursor()
+	cursor.Hide()
+	defer cursor.Show()
+
+	if err := t.Render(TableSelectTemplate, t.templateData(config)); err != nil {
+		return nil, err
+	}
+
+	rr := t.NewRuneReader()
+	_ = rr.SetTermMode()
+	defer func() {
+		_ = rr.RestoreTermMode()
+	}()
+
+	for {
+		r, _, err := rr.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+		if r == terminal.KeyInterrupt {
+			return nil, terminal.InterruptErr
+		}
+		if r == terminal.KeyEndTransmission || t.handleKey(r, config.HelpInput) {
+			break
+		}
+		_ = t.Render(TableSelectTemplate, t.templateData(config))
+	}
+
+	return t.answer(), nil
+}
+
+// Cleanup renders the prompt with the picked row's key
+func (t *TableSelect) Cleanup(config *survey.PromptConfig, val interface{}) error {
+	data := t.templateData(config)
+	data.ShowAnswer = true
+	data.Answer = val.(core.OptionAnswer).Value
+	return t.Render(TableSelectTemplate, data)
+}
+
+// handleKey updates the prompt for a key press and reports whether a row was picked
+func (t *TableSelect) handleKey(key rune, helpInput string) bool {
+	oldFilter := string(t.filter)
+
+	switch {
+	case key == terminal.KeyEnter || key == '\n':
+		return true
+	case key == terminal.KeyArrowUp:
+		t.focus = (t.focus - 1 + len(t.matches)) % len(t.matches)
+	case key == terminal.KeyArrowDown || key == terminal.KeyTab:
+		t.focus = (t.focus + 1) % len(t.matches)
+	case string(key) == helpInput && t.Help != "" && len(t.filter) == 0:
+		t.showingHelp = true
+	case key == terminal.KeyDeleteWord || key == terminal.KeyDeleteLine || key == terminal.KeyEscape:
+		t.filter = nil
+	case key == terminal.KeyDelete || key == terminal.KeyBackspace:
+		if len(t.filter) > 0 {
+			t.filter = t.filter[:len(t.filter)-1]
+		}
+	case key >= terminal.KeySpace:
+		t.filter = append(t.filter, key)
+	}
+
+	if string(t.filter) != oldFilter {
+		t.refresh()
+	}
+	return false
+}
+
+// answer returns the focused row, or the exit option
+func (t *TableSelect) answer() core.OptionAnswer {
+	match := t.matches[t.focus]
+	if match < 0 {
+		return core.OptionAnswer{Value: ExitPrompt, Index: -1}
+	}
+	return core.OptionAnswer{Value: t.Keys[match], Index: match}
+}
+
+// refresh filters the rows and renders the matching ones as a table. The exit option is always last.
+func (t *TableSelect) refresh() {
+	t.matches = t.matches[:0]
+	filter := string(t.filter)
+
+	tw := table.NewWriter()
+	tw.SetOutputMirror(io.Discard)
+	tw.SetStyle(t.Style)
+
+	header := table.Row{}
+	for _, column := range t.Columns {
+		header = append(header, column)
+	}
+	tw.AppendHeader(header)
+
+	for i, row := range t.Rows {
+		cells := make(table.Row, len(t.Columns))
+		text := make([]string, len(t.Columns))
+		for c, column := range t.Columns {
+			text[c] = formatTableCell(row[column])
+			cells[c] = truncateTableCell(text[c])
+		}
+		if filter != "" && !filterOptionsBySubstring(filter, strings.Join(text, " "), i) {
+			continue
+		}
+		t.matches = append(t.matches, i)
+		tw.AppendRow(cells)
+	}
+
+	lines := strings.Split(tw.Render(), "\n")
+	rowCount := len(t.matches)
+	headerCount := len(lines) - rowCount - 1
+	if rowCount == 0 {
+		headerCount = len(lines)
+	}
+	t.header = lines[:headerCount]
+	t.rowLines = lines[headerCount : headerCount+rowCount]
+	t.footer = lines[headerCount+rowCount:]
+
+	t.matches = append(t.matches, -1)
+	t.focus = 0
+}
+
+// templateData builds the visible page of rows around the focused one
+func (t *TableSelect) templateData(config *survey.PromptConfig) tableSelectTemplateData {
+	pageSize := t.PageSize
+	if pageSize <= 0 {
+		pageSize = config.PageSize
+	}
+
+	start := 0
+	if t.focus >= pageSize {
+		start = t.focus - pageSize + 1
+	}
+	end := start + pageSize
+	if end > len(t.rowLines) {
+		end = len(t.rowLines)
+	}
+
+	rows := make([]tableSelectLine, 0, pageSize)
+	for i := start; i < end; i++ {
+		rows = append(rows, tableSelectLine{Text: t.rowLines[i], Focused: i == t.focus})
+	}
+
+	return tableSelectTemplateData{
+		Message:     t.Message,
+		Filter:      string(t.filter),
+		Help:        t.Help,
+		Matched:     len(t.matches) - 1,
+		Total:       len(t.Rows),
+		Header:      t.header,
+		Rows:        rows,
+		Footer:      t.footer,
+		ExitFocused: t.matches[t.focus] < 0,
+		ShowHelp:    t.showingHelp,
+		Config:      config,
+	}
+}
@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+)
+
+// hostnamePattern matches an RFC 1123 host name label
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// promptRule checks the values of an answer. Single-value prompts pass one value, multiselect prompts pass one
+// value per selected option.
+type promptRule func(values []string, multi bool) error
+
+// promptValidator compiles the validators declared on a prompt into a single survey validator
+func promptValidator(p Prompt, ctx *ExecutionContext) (survey.Validator, error) {
+	rules := make([]promptRule, 0, len(p.Validators))
+	for _, v := range p.Validators {
+		rule, err := compilePromptRule(v, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("prompt '%s': %w", p.Name, err)
+		}
+		rules = append(rules, withRuleMessage(rule, v.Message))
+	}
+
+	return func(val interface{}) error {
+		values, multi := promptAnswerValues(val)
+		for _, value := range values {
+			if value == ExitPrompt {
+				return nil
+			}
+		}
+		for _, rule := range rules {
+			if err := rule(values, multi); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// compilePromptRule builds the check for a single validator definition
+func compilePromptRule(v PromptValidator, ctx *ExecutionContext) (promptRule, error) {
+	switch v.Type {
+	case "required":
+		return func(values []string, multi bool) error {
+			if multi && len(values) == 0 {
+				return fmt.Errorf("select at least one option")
+			}
+			if !multi && (len(values) == 0 || strings.TrimSpace(values[0]) == "") {
+				return fmt.Errorf("value is required")
+			}
+			return nil
+		}, nil
+
+	case "regex", "pattern":
+		re, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validator pattern %q: %w", v.Pattern, err)
+		}
+		return eachValue(func(value string) error {
+			if !re.MatchString(value) {
+				return fmt.Errorf("value must match pattern %s", v.Pattern)
+			}
+			return nil
+		}), nil
+
+	case "length":
+		return func(values []string, multi bool) error {
+			if multi {
+				return checkBounds(len(values), v.Min, v.Max, "select at least %d options", "select at most %d options")
+			}
+			length := 0
+			if len(values) > 0 {
+				length = utf8.RuneCountInString(values[0])
+			}
+			return checkBounds(length, v.Min, v.Max, "value must be at least %d characters", "value must be at most %d characters")
+		}, nil
+
+	case "range":
+		return eachValue(func(value string) error {
+			num, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return fmt.Errorf("please enter a valid number")
+			}
+			if v.Min != 0 && num < float64(v.Min) {
+				return fmt.Errorf("value must be at least %d", v.Min)
+			}
+			if v.Max != 0 && num > float64(v.Max) {
+				return fmt.Errorf("value must be at most %d", v.Max)
+			}
+			return nil
+		}), nil
+
+	case "email":
+		return eachValue(func(value string) error {
+			addr, err := mail.ParseAddress(value)
+			if err != nil || addr.Address != value {
+				return fmt.Errorf("please enter a valid email address")
+			}
+			return nil
+		}), nil
+
+	case "url":
+		return eachValue(func(value string) error {
+			u, err := url.Parse(value)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("please enter a valid URL")
+			}
+			return nil
+		}), nil
+
+	case "hostname":
+		return eachValue(func(value string) error {
+			if !isValidHostname(value) {
+				return fmt.Errorf("please enter a valid hostname")
+			}
+			return nil
+		}), nil
+
+	case "one_of":
+		if len(v.Options) == 0 {
+			return nil, fmt.Errorf("one_of validator requires options")
+		}
+		return eachValue(func(value string) error {
+			for _, option := range v.Options {
+				if value == option {
+					return nil
+				}
+			}
+			return fmt.Errorf("value must be one of: %s", strings.Join(v.Options, ", "))
+		}), nil
+
+	case "command":
+		if v.Command == "" {
+			return nil, fmt.Errorf("command validator requires a command")
+		}
+		return eachValue(func(value string) error {
+			return runValidatorCommand(v.Command, value, ctx)
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown validator type: %s", v.Type)
+	}
+}
+
+// withRuleMessage replaces the error of a failing rule with the validator's custom message
+func withRuleMessage(rule promptRule, message string) promptRule {
+	if message == "" {
+		return rule
+	}
+	return func(values []string, multi bool) error {
+		if err := rule(values, multi); err != nil {
+			return fmt.Errorf("%s", message)
+		}
+		return nil
+	}
+}
+
+// eachValue applies a check to every non-empty value. Empty answers are left to the required validator.
+func eachValue(check func(string) error) promptRule {
+	return func(values []string, multi bool) error {
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			if err := check(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// checkBounds checks a count against optional minimum and maximum values, where 0 means unbounded
+func checkBounds(n, minValue, maxValue int, minMsg, maxMsg string) error {
+	if minValue != 0 && n < minValue {
+		return fmt.Errorf(minMsg, minValue)
+	}
+	if maxValue != 0 && n > maxValue {
+		return fmt.Errorf(maxMsg, maxValue)
+	}
+	return nil
+}
+
+// isValidHostname reports whether a value is a valid RFC 1123 host name
+func isValidHostname(value string) bool {
+	value = strings.TrimSuffix(value, ".")
+	if value == "" || len(value) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(value, ".") {
+		if !hostnamePattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// runValidatorCommand runs a validation command with the value as $1 and on stdin. Exit status 0 means valid;
+// otherwise the command's output is used as the error message.
+func runValidatorCommand(command, value string, ctx *ExecutionContext) error {
+	workdir := ""
+	if ctx != nil {
+		vars := withTemplateVars(ctx.templateVars(), map[string]interface{}{"value": value})
+		rendered, err := renderTemplate(command, vars)
+		if err != nil {
+			return fmt.Errorf("validator command failed: %v", err)
+		}
+		command = rendered
+		workdir = ctx.recipeWorkdir()
+	}
+
+	cmd := exec.Command(ExecShell, "-c", command, "shef", value)
+	cmd.Dir = workdir
+	cmd.Env = os.Environ()
+	cmd.Stdin = strings.NewReader(value)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		Log(CategoryCommand, fmt.Sprintf("Prompt validator command rejected value: %s", command), map[string]interface{}{
+			"error":  err.Error(),
+			"output": output.String(),
+		})
+		if msg := strings.TrimSpace(output.String()); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("value is not valid")
+	}
+	return nil
+}
+
+// promptAnswerValues extracts the string values of a survey answer and whether it came from a multi-value prompt
+func promptAnswerValues(val interface{}) ([]string, bool) {
+	switch v := val.(type) {
+	case string:
+		return []string{v}, false
+	case core.OptionAnswer:
+		return []string{v.Value}, false
+	case []core.OptionAnswer:
+		values := make([]string, len(v))
+		for i, answer := range v {
+			values[i] = answer.Value
+		}
+		return values, true
+	case []string:
+		return v, true
+	default:
+		return []string{fmt.Sprintf("%v", v)}, false
+	}
+}
+
+// promptAnswerFromFlag converts a value passed on the command line into a prompt answer, applying the same
+// checks an interactive answer would get
+func promptAnswerFromFlag(p Prompt, raw interface{}, validator survey.Validator, ctx *ExecutionContext) (interface{}, error) {
+	str := fmt.Sprintf("%v", raw)
+	var answer interface{} = str
+
+	switch p.Type {
+	case "confirm":
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", str)
+		}
+		return b, nil
+
+	case "number":
+		if err := numberValidator(p.MinValue, p.MaxValue)(str); err != nil {
+			return nil, err
+		}
+		num, _ := strconv.Atoi(str)
+		answer = num
+
+	case "path":
+		if err := pathValidator(p.Required, p.FileExtensions)(str); err != nil {
+			return nil, err
+		}
+
+	case "select", "autocomplete", "fuzzy":
+		if p.Multiple {
+			selected, err := promptOptionsFromFlag(p, str, ctx)
+			if err != nil {
+				return nil, err
+			}
+			answer = selected
+			break
+		}
+		options, _, err := getPromptOptions(p, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPromptOption(str, options); err != nil {
+			return nil, err
+		}
+
+	case "table_select":
+		if err := validator(str); err != nil {
+			return nil, err
+		}
+		return tableSelectAnswerFromFlag(p, str, ctx)
+
+	case "multiselect":
+		selected, err := promptOptionsFromFlag(p, str, ctx)
+		if err != nil {
+			return nil, err
+		}
+		answer = selected
+
+	case "date", "datetime":
+		if err := validator(str); err != nil {
+			return nil, err
+		}
+		return parseDateAnswer(p, str)
+
+	case "duration":
+		if err := validator(str); err != nil {
+			return nil, err
+		}
+		return parseDurationAnswer(str)
+
+	case "list":
+		answer = parseListAnswer(str)
+
+	case "keyvalue":
+		pairs, err := parseKeyValueAnswer(str)
+		if err != nil {
+			return nil, err
+		}
+		if err := validator(parseListAnswer(str)); err != nil {
+			return nil, err
+		}
+		return pairs, nil
+	}
+
+	if err := validator(answer); err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// promptOptionsFromFlag splits a comma-separated command line answer into the selected options of a prompt
+func promptOptionsFromFlag(p Prompt, str string, ctx *ExecutionContext) ([]string, error) {
+	options, _, err := getPromptOptions(p, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []string{}
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		if err := checkPromptOption(item, options); err != nil {
+			return nil, err
+		}
+		selected = append(selected, item)
+	}
+	if p.MultipleLimit > 0 && len(selected) > p.MultipleLimit {
+		return nil, fmt.Errorf("select at most %d options", p.MultipleLimit)
+	}
+	return selected, nil
+}
+
+// checkPromptOption verifies that a value is one of a prompt's selectable options
+func checkPromptOption(value string, options []string) error {
+	var valid []string
+	for _, option := range options {
+		if option == ExitPrompt {
+			continue
+		}
+		if value == option {
+			return nil
+		}
+		valid = append(valid, option)
+	}
+	return fmt.Errorf("%q is not one of: %s", value, strings.Join(valid, ", "))
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// defaultDateFormats are the layouts of date and datetime prompts without a format
+var defaultDateFormats = map[string]string{
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02 15:04",
+}
+
+// dateAnswer is the answer to a date or datetime prompt. It prints in the prompt's format and can be passed to the
+// date template functions.
+type dateAnswer struct {
+	time.Time
+	layout string
+}
+
+// String formats the date in the prompt's format
+func (d dateAnswer) String() string {
+	return d.Format(d.layout)
+}
+
+// MarshalJSON encodes the date in the prompt's format
+func (d dateAnswer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// promptDateLayout returns the Go layout of a date or datetime prompt. Formats containing % are strftime patterns.
+func promptDateLayout(p Prompt) string {
+	if p.Format == "" {
+		return defaultDateFormats[p.Type]
+	}
+	return strftimeLayout(p.Format)
+}
+
+// resolveDate parses a date in a layout. It also accepts now, today, and offsets from now such as +7d or -2h.
+// The result is truncated to the precision of the layout.
+func resolveDate(value, layout string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	now := time.Now()
+
+	var t time.Time
+	switch {
+	case value == "now":
+		t = now
+	case value == "today":
+		t = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	case strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-"):
+		d, err := parseDurationWithDays(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date offset %q", value)
+		}
+		t = now.Add(d)
+	default:
+		parsed, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expected a date like %s", now.Format(layout))
+		}
+		return parsed, nil
+	}
+
+	truncated, err := time.ParseInLocation(layout, t.Format(layout), time.Local)
+	if err != nil {
+		return t, nil
+	}
+	return truncated, nil
+}
+
+// parseDateAnswer parses the answer to a date or datetime prompt and checks it against the prompt's bounds
+func parseDateAnswer(p Prompt, value string) (dateAnswer, error) {
+	layout := promptDateLayout(p)
+	t, err := resolveDate(value, layout)
+	if err != nil {
+		return dateAnswer{}, err
+	}
+
+	if p.MinDate != "" {
+		minDate, err := resolveDate(p.MinDate, layout)
+		if err != nil {
+			return dateAnswer{}, fmt.Errorf("invalid min_date: %w", err)
+		}
+		if t.Before(minDate) {
+			return dateAnswer{}, fmt.Errorf("date must be on or after %s", minDate.Format(layout))
+		}
+	}
+	if p.MaxDate != "" {
+		maxDate, err := resolveDate(p.MaxDate, layout)
+		if err != nil {
+			return dateAnswer{}, fmt.Errorf("invalid max_date: %w", err)
+		}
+		if t.After(maxDate) {
+			return dateAnswer{}, fmt.Errorf("date must be on or before %s", maxDate.Format(layout))
+		}
+	}
+
+	return dateAnswer{Time: t, layout: layout}, nil
+}
+
+// parseDurationAnswer parses the answer to a duration prompt, such as 90s, 1h30m or 7d
+func parseDurationAnswer(value string) (time.Duration, error) {
+	d, err := parseDurationWithDays(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration such as 30m, 1h30m or 7d")
+	}
+	return d, nil
+}
+
+// parseListAnswer splits the answer to a list prompt into items. Items are separated by newlines, or by commas when
+// the answer is a single line. Blank items are dropped.
+func parseListAnswer(value string) []string {
+	separator := ","
+	if strings.Contains(value, "\n") {
+		separator = "\n"
+	}
+
+	items := []string{}
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseKeyValueAnswer parses the key=value entries of a keyvalue prompt into a map. A repeated key keeps its last
+// value.
+func parseKeyValueAnswer(value string) (map[string]interface{}, error) {
+	pairs := make(map[string]interface{})
+	for _, entry := range parseListAnswer(value) {
+		key, val, found := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", entry)
+		}
+		pairs[key] = strings.TrimSpace(val)
+	}
+	return pairs, nil
+}
+
+// handleDatePrompt displays a date or datetime input and returns the parsed date
+func handleDatePrompt(p Prompt, message, defaultValue, helpText string, validator survey.Validator) (interface{}, error) {
+	if defaultValue != "" {
+		if d, err := parseDateAnswer(p, defaultValue); err == nil {
+			defaultValue = d.String()
+		}
+	}
+	if helpText == "" {
+		helpText = fmt.Sprintf("Enter a date like %s, today, or an offset such as +7d", time.Now().Format(promptDateLayout(p)))
+	}
+
+	var answer string
+	prompt := &survey.Input{
+		Message: message,
+		Default: defaultValue,
+		Help:    helpText,
+	}
+
+	validator = survey.ComposeValidators(func(val interface{}) error {
+		_, err := parseDateAnswer(p, fmt.Sprintf("%v", val))
+		return err
+	}, validator)
+
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
+		return nil, err
+	}
+	return parseDateAnswer(p, answer)
+}
+
+// handleDurationPrompt displays a duration input and returns the parsed duration
+func handleDurationPrompt(message, defaultValue, helpText string, validator survey.Validator) (interface{}, error) {
+	var answer string
+	prompt := &survey.Input{
+		Message: message,
+		Default: defaultValue,
+		Help:    helpText,
+	}
+
+	validator = survey.ComposeValidators(func(val interface{}) error {
+		_, err := parseDurationAnswer(fmt.Sprintf("%v", val))
+		return err
+	}, validator)
+
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
+		return nil, err
+	}
+	return parseDurationAnswer(answer)
+}
+
+// handleListPrompt reads items one per line and returns them as a list
+func handleListPrompt(message, defaultValue, helpText string, validator survey.Validator) (interface{}, error) {
+	answer, err := askMultiline(message, defaultValue, helpText, func(val interface{}) error {
+		return validator(parseListAnswer(fmt.Sprintf("%v", val)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseListAnswer(answer), nil
+}
+
+// handleKeyValuePrompt reads key=value pairs one per line and returns them as a map
+func handleKeyValuePrompt(message, defaultValue, helpText string, validator survey.Validator) (interface{}, error) {
+	answer, err := askMultiline(message, defaultValue, helpText, func(val interface{}) error {
+		str := fmt.Sprintf("%v", val)
+		if _, err := parseKeyValueAnswer(str); err != nil {
+			return err
+		}
+		return validator(parseListAnswer(str))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyValueAnswer(answer)
+}
+
+// askMultiline reads lines until two empty lines are entered. A default is shown on a single line, comma-separated.
+func askMultiline(message, defaultValue, helpText string, validator survey.Validator) (string, error) {
+	var answer string
+	prompt := &survey.Multiline{
+		Message: message,
+		Default: strings.Join(parseListAnswer(defaultValue), ", "),
+		Help:    helpText,
+	}
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
+		return "", err
+	}
+	return answer, nil
+}
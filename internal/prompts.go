@@ -24,51 +24,72 @@ func handlePrompt(p Prompt, ctx *ExecutionContext) (interface{}, error) {
 		return nil, err
 	}
 
+	if remembered, ok := ctx.promptMemory.recall(p); ok {
+		defaultValue = remembered
+	}
+
 	helpText, err := renderTemplate(p.HelpText, vars)
 	if err != nil {
 		return nil, err
 	}
 
+	validator, err := promptValidator(p, ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	switch p.Type {
 	case "input":
-		return handleInputPrompt(message, defaultValue, helpText)
+		return handleInputPrompt(message, defaultValue, helpText, validator)
 	case "select":
-		return handleSelectPrompt(p, ctx, message, defaultValue, helpText)
+		return handleSelectPrompt(p, ctx, message, defaultValue, helpText, validator)
 	case "confirm":
 		return handleConfirmPrompt(message, defaultValue, helpText)
 	case "password":
-		return handlePasswordPrompt(message, helpText)
+		return handlePasswordPrompt(message, helpText, validator)
 	case "multiselect":
-		return handleMultiselectPrompt(p, ctx, message, defaultValue, helpText)
+		return handleMultiselectPrompt(p, ctx, message, defaultValue, helpText, validator)
 	case "number":
-		return handleNumberPrompt(p, message, defaultValue, helpText)
+		return handleNumberPrompt(p, message, defaultValue, helpText, validator)
 	case "editor":
-		return handleEditorPrompt(p, message, defaultValue, helpText)
+		return handleEditorPrompt(p, message, defaultValue, helpText, validator)
 	case "path":
-		return handlePathPrompt(p, message, defaultValue, helpText)
+		return handlePathPrompt(p, message, defaultValue, helpText, validator)
 	case "autocomplete":
-		return handleAutocompletePrompt(p, ctx, message, defaultValue, helpText)
+		return handleAutocompletePrompt(p, ctx, message, defaultValue, helpText, validator)
+	case "fuzzy":
+		return handleFuzzyPrompt(p, ctx, message, defaultValue, helpText, validator)
+	case "table_select":
+		return handleTableSelectPrompt(p, ctx, message, defaultValue, helpText, validator)
+	case "date", "datetime":
+		return handleDatePrompt(p, message, defaultValue, helpText, validator)
+	case "duration":
+		return handleDurationPrompt(message, defaultValue, helpText, validator)
+	case "list":
+		return handleListPrompt(message, defaultValue, helpText, validator)
+	case "keyvalue":
+		return handleKeyValuePrompt(message, defaultValue, helpText, validator)
 	default:
 		return nil, fmt.Errorf("unknown prompt type: %s", p.Type)
 	}
 }
 
 // handleInputPrompt displays a simple text input prompt
-func handleInputPrompt(message, defaultValue, helpText string) (string, error) {
+func handleInputPrompt(message, defaultValue, helpText string, validator survey.Validator) (string, error) {
 	var answer string
 	prompt := &survey.Input{
 		Message: message,
 		Default: defaultValue,
 		Help:    helpText,
 	}
-	if err := survey.AskOne(prompt, &answer); err != nil {
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return "", err
 	}
 	return answer, nil
 }
 
 // handleSelectPrompt displays a selection menu prompt
-func handleSelectPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string) (string, error) {
+func handleSelectPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string, validator survey.Validator) (string, error) {
 	options, descriptions, err := getPromptOptions(p, ctx)
 	if err != nil {
 		return "", err
@@ -91,7 +112,7 @@ func handleSelectPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue,
 		}
 	}
 
-	if err := survey.AskOne(prompt, &answer); err != nil {
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return "", err
 	}
 	return answer, nil
@@ -112,20 +133,20 @@ func handleConfirmPrompt(message, defaultValue, helpText string) (bool, error) {
 }
 
 // handlePasswordPrompt displays a masked password input prompt
-func handlePasswordPrompt(message, helpText string) (string, error) {
+func handlePasswordPrompt(message, helpText string, validator survey.Validator) (string, error) {
 	var answer string
 	prompt := &survey.Password{
 		Message: message,
 		Help:    helpText,
 	}
-	if err := survey.AskOne(prompt, &answer); err != nil {
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return "", err
 	}
 	return answer, nil
 }
 
 // handleMultiselectPrompt displays a multi-option selection prompt
-func handleMultiselectPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string) ([]string, error) {
+func handleMultiselectPrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string, validator survey.Validator) ([]string, error) {
 	options, descriptions, err := getPromptOptions(p, ctx)
 	if err != nil {
 		return nil, err
@@ -148,14 +169,14 @@ func handleMultiselectPrompt(p Prompt, ctx *ExecutionContext, message, defaultVa
 		}
 	}
 
-	if err := survey.AskOne(prompt, &answer); err != nil {
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return nil, err
 	}
 	return answer, nil
 }
 
 // handleNumberPrompt displays a numeric input prompt with validation
-func handleNumberPrompt(p Prompt, message, defaultValue, helpText string) (int, error) {
+func handleNumberPrompt(p Prompt, message, defaultValue, helpText string, validator survey.Validator) (int, error) {
 	var answer int
 	prompt := &survey.Input{
 		Message: message,
@@ -163,7 +184,7 @@ func handleNumberPrompt(p Prompt, message, defaultValue, helpText string) (int,
 		Help:    helpText,
 	}
 
-	validator := numberValidator(p.MinValue, p.MaxValue)
+	validator = survey.ComposeValidators(numberValidator(p.MinValue, p.MaxValue), validator)
 
 	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return 0, err
@@ -172,7 +193,7 @@ func handleNumberPrompt(p Prompt, message, defaultValue, helpText string) (int,
 }
 
 // handleEditorPrompt displays a text editor for multi-line input
-func handleEditorPrompt(p Prompt, message, defaultValue, helpText string) (string, error) {
+func handleEditorPrompt(p Prompt, message, defaultValue, helpText string, validator survey.Validator) (string, error) {
 	var answer string
 	editorCmd := getEditorCommand(p.EditorCmd)
 
@@ -184,14 +205,14 @@ func handleEditorPrompt(p Prompt, message, defaultValue, helpText string) (strin
 		AppendDefault: true,
 		Editor:        editorCmd,
 	}
-	if err := survey.AskOne(prompt, &answer); err != nil {
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return "", err
 	}
 	return answer, nil
 }
 
 // handlePathPrompt displays a file path input with validation
-func handlePathPrompt(p Prompt, message, defaultValue, helpText string) (string, error) {
+func handlePathPrompt(p Prompt, message, defaultValue, helpText string, validator survey.Validator) (string, error) {
 	var answer string
 	prompt := &survey.Input{
 		Message: message,
@@ -199,7 +220,7 @@ func handlePathPrompt(p Prompt, message, defaultValue, helpText string) (string,
 		Help:    helpText,
 	}
 
-	validator := pathValidator(p.Required, p.FileExtensions)
+	validator = survey.ComposeValidators(pathValidator(p.Required, p.FileExtensions), validator)
 
 	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return "", err
@@ -208,7 +229,7 @@ func handlePathPrompt(p Prompt, message, defaultValue, helpText string) (string,
 }
 
 // handleAutocompletePrompt displays a filterable selection menu
-func handleAutocompletePrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string) (string, error) {
+func handleAutocompletePrompt(p Prompt, ctx *ExecutionContext, message, defaultValue, helpText string, validator survey.Validator) (string, error) {
 	options, descriptions, err := getPromptOptions(p, ctx)
 	if err != nil {
 		return "", err
@@ -232,7 +253,7 @@ func handleAutocompletePrompt(p Prompt, ctx *ExecutionContext, message, defaultV
 		}
 	}
 
-	if err := survey.AskOne(prompt, &answer); err != nil {
+	if err := survey.AskOne(prompt, &answer, survey.WithValidator(validator)); err != nil {
 		return "", err
 	}
 	return answer, nil
@@ -283,48 +304,114 @@ func parseDefaultOptions(defaultValue string, options []string) []string {
 // getPromptOptions retrieves the options for selection-type prompts
 func getPromptOptions(p Prompt, ctx *ExecutionContext) ([]string, map[string]string, error) {
 	if p.SourceOp == "" {
-		var options []string
-		if len(p.Options) > 0 && p.Type != "multiselect" {
-			options = append(p.Options, ExitPrompt)
-		} else {
-			options = p.Options
+		options, descriptions, err := renderPromptOptions(p, ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(options) > 0 && p.Type != "multiselect" {
+			options = append(options, ExitPrompt)
 		}
-		return options, p.Descriptions, nil
+		return options, descriptions, nil
 	}
 
 	return getOptionsFromSourceOp(p, ctx)
 }
 
+// renderPromptOptions renders templated options with the current variables, so options can depend on answers to
+// earlier prompts. An option that renders to several lines adds one option per line, using the same value=description
+// format as source operation output.
+func renderPromptOptions(p Prompt, ctx *ExecutionContext) ([]string, map[string]string, error) {
+	options := make([]string, 0, len(p.Options))
+	descriptions := p.Descriptions
+	var vars map[string]interface{}
+
+	for _, option := range p.Options {
+		if !hasTemplateActions(option) {
+			options = append(options, option)
+			continue
+		}
+
+		if vars == nil {
+			vars = ctx.templateVars()
+		}
+		rendered, err := renderTemplate(option, vars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to render option %q: %w", option, err)
+		}
+
+		renderedOptions, renderedDescriptions := parseSelectOptionsFromOutput(rendered)
+		options = append(options, renderedOptions...)
+		if len(renderedDescriptions) > 0 {
+			descriptions = withOptionDescriptions(descriptions, renderedDescriptions)
+		}
+	}
+
+	if len(p.Options) > 0 && len(options) == 0 {
+		return nil, nil, fmt.Errorf("no options available for prompt %s", p.Name)
+	}
+
+	return options, descriptions, nil
+}
+
+// withOptionDescriptions returns a copy of the declared descriptions with rendered ones added
+func withOptionDescriptions(declared, rendered map[string]string) map[string]string {
+	merged := make(map[string]string, len(declared)+len(rendered))
+	for k, v := range declared {
+		merged[k] = v
+	}
+	for k, v := range rendered {
+		merged[k] = v
+	}
+	return merged
+}
+
 // getOptionsFromSourceOp extracts options from a source operation's output
 func getOptionsFromSourceOp(p Prompt, ctx *ExecutionContext) ([]string, map[string]string, error) {
+	output, sourceOp, err := sourceOperationOutput(p, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options, descriptions := parseSelectOptionsFromOutput(output)
+	if len(options) == 0 && p.SourceTransform == "" {
+		return nil, nil, fmt.Errorf("no options found from source operation %s", sourceOp)
+	}
+
+	if len(options) > 0 && p.Type != "multiselect" {
+		options = append(options, ExitPrompt)
+	}
+
+	return options, descriptions, nil
+}
+
+// sourceOperationOutput returns the output of a prompt's source operation after its source transform. The source
+// operation ID is rendered first, so it can depend on earlier answers.
+func sourceOperationOutput(p Prompt, ctx *ExecutionContext) (string, string, error) {
+	sourceOp := p.SourceOp
+	if hasTemplateActions(sourceOp) {
+		rendered, err := renderTemplate(sourceOp, ctx.templateVars())
+		if err != nil {
+			return "", "", fmt.Errorf("failed to render source operation %q: %w", sourceOp, err)
+		}
+		sourceOp = strings.TrimSpace(rendered)
+	}
+
 	ctx.OperationMutex.RLock()
-	output, exists := ctx.OperationOutputs[p.SourceOp]
+	output, exists := ctx.OperationOutputs[sourceOp]
 	ctx.OperationMutex.RUnlock()
 	if !exists {
-		return nil, nil, fmt.Errorf("source operation %s not found or has no output", p.SourceOp)
+		return "", "", fmt.Errorf("source operation %s not found or has no output", sourceOp)
 	}
 
-	var options []string
-	var descriptions map[string]string
-
 	if p.SourceTransform != "" {
 		transformed, err := transformOutput(output, p.SourceTransform, ctx)
 		if err != nil {
-			return nil, nil, fmt.Errorf("transformation failed: %w", err)
-		}
-		options, descriptions = parseSelectOptionsFromOutput(transformed)
-	} else {
-		options, descriptions = parseSelectOptionsFromOutput(output)
-		if len(options) == 0 {
-			return nil, nil, fmt.Errorf("no options found from source operation %s", p.SourceOp)
+			return "", "", fmt.Errorf("transformation failed: %w", err)
 		}
+		output = transformed
 	}
 
-	if len(options) > 0 && p.Type != "multiselect" {
-		options = append(options, ExitPrompt)
-	}
-
-	return options, descriptions, nil
+	return output, sourceOp, nil
 }
 
 // finalizeOptions adds exit option if needed and returns the final options list
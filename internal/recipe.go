@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -22,18 +24,40 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 		Data:                          "",
 		Vars:                          make(map[string]interface{}),
 		OperationOutputs:              make(map[string]string),
+		OperationData:                 make(map[string]interface{}),
 		OperationResults:              make(map[string]bool),
 		LoopStack:                     make([]*LoopContext, 0),
 		ExecutedOperationsByComponent: make(map[string][]string),
+		RunID:                         uuid.New().String(),
+		Strict:                        recipe.Strict,
+		DryRun:                        recipe.DryRun,
+		promptMemory:                  newPromptMemory(recipe),
 	}
 
 	ctx.templateFuncs = extendTemplateFuncs(templateFuncs, ctx)
+
+	ctx.promptAnswers = make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		ctx.promptAnswers[k] = v
+	}
 	vars["context"] = ctx
 
+	switch recipe.Stdin {
+	case "", StdinPrevious, StdinNone:
+		ctx.DefaultStdin = recipe.Stdin
+	default:
+		return fmt.Errorf("invalid recipe stdin value: %s (expected previous or none)", recipe.Stdin)
+	}
+
+	if recipe.MaxBackground > 0 {
+		Log(CategoryBackground, fmt.Sprintf("Limiting concurrent background tasks to %d", recipe.MaxBackground))
+		ctx.BackgroundSlots = make(chan struct{}, recipe.MaxBackground)
+	}
+
 	if recipe.Vars != nil {
 		Log(CategoryRecipe, fmt.Sprintf("Adding %d recipe variables", len(recipe.Vars)))
 		for k, v := range recipe.Vars {
-			ctx.Vars[k] = v
+			ctx.setVar(k, v)
 		}
 	}
 
@@ -43,17 +67,17 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 			LogError("Failed to create working directory", err, map[string]interface{}{"workdir": recipe.Workdir})
 			return err
 		}
-		ctx.Vars["workdir"] = recipe.Workdir
+		ctx.setVar("workdir", recipe.Workdir)
 	}
 
 	Log(CategoryRecipe, fmt.Sprintf("Adding %d external variables", len(vars)))
 	for k, v := range vars {
-		ctx.Vars[k] = v
+		ctx.setVar(k, v)
 	}
 
 	if input != "" {
 		Log(CategoryRecipe, "Setting input data")
-		ctx.Vars["input"] = input
+		ctx.setVar("input", input)
 		ctx.Data = input
 	}
 
@@ -72,6 +96,9 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 	}
 
 	registerOperations(expandedOperations, opMap)
+	for id := range opMap {
+		ctx.operationIDs = append(ctx.operationIDs, id)
+	}
 
 	handlerIDs := make(map[string]bool)
 	identifyHandlers(expandedOperations, handlerIDs)
@@ -79,7 +106,13 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 	printRegisteredOperations(opMap, handlerIDs)
 
 	var executeOp func(op Operation, depth int) (bool, error)
-	executeOp = func(op Operation, depth int) (bool, error) {
+	executeOp = func(op Operation, depth int) (exit bool, err error) {
+		defer func() {
+			if err != nil && ctx.Strict {
+				err = annotateUndefinedVariable(err, op.Name)
+			}
+		}()
+
 		if depth > 50 {
 			LogError("Possible infinite loop detected", nil, map[string]interface{}{"depth": depth})
 			return false, fmt.Errorf("possible infinite loop detected (max depth reached)")
@@ -97,7 +130,6 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 
 		renderedID := op.ID
 		if op.ID != "" {
-			var err error
 			originalID := op.ID
 			renderedID, err = renderTemplate(op.ID, ctx.templateVars())
 			if err != nil {
@@ -113,17 +145,50 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 			}
 		}
 
+		// exec calls in the condition and prompts use the recipe workdir, since the operation's workdir and exec_timeout
+		// can depend on prompt answers
+		execOpts := execOptions{Operation: op.Name, Workdir: ctx.recipeWorkdir(), UserShell: op.UserShell}
+		previousExecOpts := ctx.setExecOptions(&execOpts)
+		defer ctx.setExecOptions(previousExecOpts)
+
 		// 1. Check condition
 		if !shouldRunOperation(op, ctx) {
 			return false, nil
 		}
 
+		if op.ExecTimeout != "" {
+			execTimeout, err := parseTaskTimeout(op.ExecTimeout, ctx)
+			if err != nil {
+				return false, err
+			}
+			timedExecOpts := ctx.currentExecOptions()
+			timedExecOpts.Timeout = execTimeout
+			ctx.setExecOptions(&timedExecOpts)
+		}
+
 		// 2. Handle prompts
 		if err := processPrompts(op, ctx); err != nil {
 			return false, err
 		}
 
-		// 3. Process control flow
+		// 3. Attach to, wait for or cancel background tasks
+		if op.Attach != "" {
+			if err := attachToBackgroundTask(op, ctx); err != nil {
+				return false, err
+			}
+		}
+		if len(op.WaitFor) > 0 {
+			if err := waitForBackgroundTasks(op, ctx); err != nil {
+				return false, err
+			}
+		}
+		if op.Cancel != "" {
+			if err := cancelBackgroundTask(op, ctx); err != nil {
+				return false, err
+			}
+		}
+
+		// 4. Process control flow
 		if op.ControlFlow != nil {
 			exit, err := processControlFlow(op, ctx, depth, executeOp)
 			if err != nil {
@@ -135,19 +200,7 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 			}
 		}
 
-		// 4. Prepare command
-		cmd := op.Command
-		var err error
-		if !op.RawCommand {
-			cmd, err = renderTemplate(op.Command, ctx.templateVars())
-			if err != nil {
-				return false, fmt.Errorf("failed to render command template: %w", err)
-			}
-		} else {
-			Log(CategoryTemplate, "Using raw command (bypassing template rendering)")
-		}
-		LogCommand(cmd, nil)
-		ctx.Vars["error"] = ""
+		// 5. Resolve working directory and prepare command
 		workdir := ""
 		if op.Workdir != "" {
 			renderedWorkdir, err := renderTemplate(op.Workdir, ctx.templateVars())
@@ -161,36 +214,60 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 				LogError("Failed to create operation working directory", err, map[string]interface{}{"workdir": workdir})
 				return false, err
 			}
-		} else if workdirVal, exists := ctx.Vars["workdir"]; exists {
-			workdir = fmt.Sprintf("%v", workdirVal)
+			workdirExecOpts := ctx.currentExecOptions()
+			workdirExecOpts.Workdir = workdir
+			ctx.setExecOptions(&workdirExecOpts)
+		} else {
+			workdir = ctx.recipeWorkdir()
 		}
 
-		// 5. Component Output Collection
+		cmd := op.Command
+		if !op.RawCommand {
+			cmd, err = renderTemplate(op.Command, ctx.templateVars())
+			if err != nil {
+				return false, fmt.Errorf("failed to render command template: %w", err)
+			}
+		} else {
+			Log(CategoryTemplate, "Using raw command (bypassing template rendering)")
+		}
+		LogCommand(cmd, nil)
+		ctx.setVar("error", "")
+		// 6. Component Output Collection
 		if op.IsComponentOutputCollector && op.ComponentInstanceID != "" {
 			return handleComponentOutputCollector(op, ctx)
 		}
 
-		// 6. Execute command in the background
+		input, err := resolveStdin(op, ctx, workdir)
+		if err != nil {
+			LogError("Failed to resolve stdin", err, map[string]interface{}{"operation": op.Name})
+			return false, err
+		}
+
+		if ctx.DryRun {
+			return executeDryRun(op, cmd, ctx, opMap, executeOp, depth)
+		}
+
+		// 7. Execute command in the background
 		if op.ExecutionMode == "background" {
-			if err := executeBackgroundCommand(op, ctx, opMap, executeOp, depth, workdir); err != nil {
+			if err := executeBackgroundCommand(op, ctx, opMap, executeOp, depth, workdir, input); err != nil {
 				return false, err
 			}
 			return op.Exit, nil
 		}
 
-		// 7. Execute command normally
-		output, err := executeCommand(cmd, ctx.Data, op.ExecutionMode, op.OutputFormat, workdir, op.UserShell, op.RawCommand)
+		// 8. Execute command normally
+		output, err := executeCommand(cmd, input, op.ExecutionMode, op.OutputFormat, workdir, op.UserShell, op.RawCommand)
 		operationSuccess := err == nil
 		if op.ID != "" {
 			ctx.OperationResults[op.ID] = operationSuccess
 		}
 
-		// 8. Handle command errors
+		// 9. Handle command errors
 		if err != nil {
 			return handleCommandError(op, ctx, opMap, executeOp, err, depth)
 		}
 
-		// 9. Process command output
+		// 10. Process command output
 		return processCommandOutput(op, output, ctx, opMap, executeOp, depth)
 	}
 
@@ -207,6 +284,10 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 			return err
 		}
 
+		if err := ctx.backgroundFailure(); err != nil {
+			return err
+		}
+
 		if shouldExit {
 			Log(CategoryRecipe, fmt.Sprintf("Exiting recipe execution after operation: %s", op.Name))
 			return nil
@@ -222,11 +303,13 @@ func evaluateRecipe(recipe Recipe, input string, vars map[string]interface{}) er
 			LogBackgroundTask(id, "completed", map[string]interface{}{"output": task.Output})
 		} else if task.Status == TaskFailed && task.Error != "" {
 			LogBackgroundTask(id, "failed", map[string]interface{}{"error": task.Error})
+		} else if task.Status == TaskCancelled {
+			LogBackgroundTask(id, "cancelled", nil)
 		}
 	}
 	ctx.BackgroundMutex.RUnlock()
 
-	return nil
+	return ctx.backgroundFailure()
 }
 
 // shouldRunOperation checks if an operation's condition is met
@@ -240,6 +323,10 @@ func shouldRunOperation(op Operation, ctx *ExecutionContext) bool {
 	result, err := evaluateCondition(op.Condition, ctx)
 	if err != nil {
 		LogError("Condition evaluation failed", err, map[string]interface{}{"condition": op.Condition})
+		var syntaxErr *conditionSyntaxError
+		if errors.As(err, &syntaxErr) {
+			fmt.Printf("Warning: Skipping operation '%s': %v\n", op.Name, err)
+		}
 		return false
 	}
 
@@ -250,28 +337,146 @@ func shouldRunOperation(op Operation, ctx *ExecutionContext) bool {
 // processPrompts handles all prompts for an operation
 func processPrompts(op Operation, ctx *ExecutionContext) error {
 	for _, prompt := range op.Prompts {
-		value, err := handlePrompt(prompt, ctx)
+		varName := promptVarName(prompt)
+
+		if !shouldAskPrompt(prompt, ctx) {
+			Log(CategoryRecipe, fmt.Sprintf("Skipping prompt '%s': condition not met", varName))
+			continue
+		}
+
+		value, err := answerPrompt(prompt, ctx)
 		if err != nil {
 			return err
 		}
 
-		if value == ExitPrompt && (prompt.Type == "select" || prompt.Type == "autocomplete") {
+		if isExitAnswer(prompt, value) {
 			os.Exit(0)
 		}
 
-		varName := prompt.Name
-		if prompt.ID != "" {
-			varName = prompt.ID
+		if !ctx.DryRun {
+			ctx.promptMemory.remember(prompt, value)
 		}
-		ctx.Vars[varName] = value
-		ctx.OperationMutex.Lock()
-		ctx.OperationOutputs[varName] = fmt.Sprintf("%v", value)
-		ctx.OperationMutex.Unlock()
+
+		ctx.storePromptAnswer(varName, value)
 	}
 
 	return nil
 }
 
+// clearPromptAnswer removes a stored prompt answer
+func (ctx *ExecutionContext) clearPromptAnswer(varName string) {
+	ctx.deleteVar(varName)
+
+	ctx.OperationMutex.Lock()
+	delete(ctx.OperationOutputs, varName)
+	delete(ctx.OperationData, varName)
+	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
+}
+
+// answerPrompt returns a prompt's answer, taken from the command line when its flag was passed and asked otherwise
+func answerPrompt(prompt Prompt, ctx *ExecutionContext) (interface{}, error) {
+	if prompt.Type == "form" {
+		return handleFormPrompt(prompt, ctx)
+	}
+
+	varName := promptVarName(prompt)
+	raw, ok := ctx.promptAnswer(varName)
+	if !ok {
+		return handlePrompt(prompt, ctx)
+	}
+
+	validator, err := promptValidator(prompt, ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, err := promptAnswerFromFlag(prompt, raw, validator, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for prompt '%s': %w", varName, err)
+	}
+	Log(CategoryRecipe, fmt.Sprintf("Prompt '%s' answered from command line", varName))
+	return value, nil
+}
+
+// storePromptAnswer stores a prompt's answer as a variable and as an operation output. Structured answers such as
+// picked table rows and lists of selections are also kept as operation data, so templates can reach into them.
+func (ctx *ExecutionContext) storePromptAnswer(varName string, value interface{}) {
+	ctx.setVar(varName, value)
+
+	output := fmt.Sprintf("%v", value)
+	structured := false
+	switch value.(type) {
+	case map[string]interface{}:
+		if data, err := json.Marshal(value); err == nil {
+			output = string(data)
+		}
+		structured = true
+	case []string, []interface{}, dateAnswer, time.Duration:
+		structured = true
+	}
+
+	ctx.OperationMutex.Lock()
+	ctx.OperationOutputs[varName] = output
+	if ctx.OperationData == nil {
+		ctx.OperationData = make(map[string]interface{})
+	}
+	if structured {
+		ctx.OperationData[varName] = value
+	} else {
+		delete(ctx.OperationData, varName)
+	}
+	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
+}
+
+// isExitAnswer reports whether the exit option was chosen in a selection prompt
+func isExitAnswer(p Prompt, value interface{}) bool {
+	switch p.Type {
+	case "select", "autocomplete", "fuzzy", "table_select", "form":
+	default:
+		return false
+	}
+
+	values, _ := promptAnswerValues(value)
+	for _, v := range values {
+		if v == ExitPrompt {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldAskPrompt checks if a prompt's condition is met. Answers to earlier prompts of the same operation are
+// already stored, so conditions can depend on them.
+func shouldAskPrompt(p Prompt, ctx *ExecutionContext) bool {
+	if p.Condition == "" {
+		return true
+	}
+
+	result, err := evaluateCondition(p.Condition, ctx)
+	if err != nil {
+		LogError("Prompt condition evaluation failed", err, map[string]interface{}{"condition": p.Condition})
+		var syntaxErr *conditionSyntaxError
+		if errors.As(err, &syntaxErr) {
+			fmt.Printf("Warning: Skipping prompt '%s': %v\n", p.Name, err)
+		}
+		return false
+	}
+
+	LogCondition(p.Condition, result, map[string]interface{}{"prompt": p.Name})
+	return result
+}
+
+// promptAnswer returns the value passed on the command line for a prompt, if any. Flag names use underscores
+// in place of dashes, so both spellings of a prompt ID are accepted.
+func (ctx *ExecutionContext) promptAnswer(varName string) (interface{}, bool) {
+	if value, ok := ctx.promptAnswers[varName]; ok {
+		return value, true
+	}
+	value, ok := ctx.promptAnswers[strings.ReplaceAll(varName, "-", "_")]
+	return value, ok
+}
+
 // processControlFlow handles foreach, while, and for loops
 func processControlFlow(op Operation, ctx *ExecutionContext, depth int, executeOp func(Operation, int) (bool, error)) (bool, error) {
 	flowMap, ok := op.ControlFlow.(map[string]interface{})
@@ -313,7 +518,7 @@ func processControlFlow(op Operation, ctx *ExecutionContext, depth int, executeO
 
 // handleCommandError processes errors from command execution
 func handleCommandError(op Operation, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), err error, depth int) (bool, error) {
-	ctx.Vars["error"] = err.Error()
+	ctx.setVar("error", err.Error())
 
 	LogError("Command execution error", err, map[string]interface{}{
 		"operation": op.Name,
@@ -337,6 +542,11 @@ func handleCommandError(op Operation, ctx *ExecutionContext, opMap map[string]Op
 		return shouldExit || op.Exit, err
 	}
 
+	if loop := ctx.currentLoop(); loop != nil && loop.OnError != "" {
+		Log(CategoryControlFlow, fmt.Sprintf("Deferring error to loop on_error policy: %s", loop.OnError))
+		return false, err
+	}
+
 	fmt.Printf("Error in operation '%s': \n%v\n", op.Name, err)
 
 	var continueExecution bool
@@ -377,6 +587,7 @@ func handleComponentOutputCollector(op Operation, ctx *ExecutionContext) (bool,
 			Log(CategoryComponent, fmt.Sprintf("Operation %s executed but didn't produce output", lastOpID))
 		}
 		ctx.OperationMutex.Unlock()
+		ctx.invalidateTemplateVars()
 	} else {
 		ctx.OperationMutex.RLock()
 		inputVal, exists := ctx.OperationOutputs[op.ID]
@@ -388,6 +599,7 @@ func handleComponentOutputCollector(op Operation, ctx *ExecutionContext) (bool,
 			ctx.OperationMutex.Lock()
 			ctx.OperationOutputs[op.ID] = ""
 			ctx.OperationMutex.Unlock()
+			ctx.invalidateTemplateVars()
 			Log(CategoryComponent, fmt.Sprintf("No operations executed in component %s", op.ComponentInstanceID))
 		}
 	}
@@ -447,14 +659,29 @@ func handleCleanup(op Operation, ctx *ExecutionContext) {
 		}
 
 		Log(CategoryOperation, fmt.Sprintf("Cleaning variable: %s", cleanupVarName))
-		delete(ctx.Vars, cleanupVarName)
+		ctx.deleteVar(cleanupVarName)
 		ctx.OperationMutex.Lock()
 		delete(ctx.OperationOutputs, cleanupVarName)
+		delete(ctx.OperationData, cleanupVarName)
 		ctx.OperationMutex.Unlock()
+		ctx.invalidateTemplateVars()
 		delete(ctx.OperationResults, cleanupVarName)
 	}
 }
 
+// executeDryRun prints the command an operation would run and continues as if it succeeded with no output
+func executeDryRun(op Operation, cmd string, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), depth int) (bool, error) {
+	if strings.TrimSpace(cmd) != "" {
+		fmt.Printf("[dry-run] %s: %s\n", op.Name, strings.TrimSpace(cmd))
+	}
+	Log(CategoryCommand, fmt.Sprintf("Skipped command (dry run): %s", cmd))
+
+	if op.ID != "" {
+		ctx.OperationResults[op.ID] = true
+	}
+	return processCommandOutput(op, "", ctx, opMap, executeOp, depth)
+}
+
 // processCommandOutput handles successful command output
 func processCommandOutput(op Operation, output string, ctx *ExecutionContext, opMap map[string]Operation, executeOp func(Operation, int) (bool, error), depth int) (bool, error) {
 	LogOutput(output, map[string]interface{}{
@@ -479,6 +706,11 @@ func processCommandOutput(op Operation, output string, ctx *ExecutionContext, op
 		ctx.OperationMutex.Lock()
 		ctx.OperationOutputs[op.ID] = strings.TrimSpace(output)
 		ctx.OperationMutex.Unlock()
+		ctx.invalidateTemplateVars()
+		if err := storeStructuredOutput(op, output, ctx); err != nil {
+			LogError("Failed to parse structured output", err, map[string]interface{}{"operation": op.Name})
+			return false, err
+		}
 		if op.ComponentInstanceID != "" {
 			ctx.ExecutedOperationsByComponent[op.ComponentInstanceID] =
 				append(ctx.ExecutedOperationsByComponent[op.ComponentInstanceID], op.ID)
@@ -557,7 +789,7 @@ func identifyHandlers(operations []Operation, handlerIDs map[string]bool) {
 	}
 }
 
-// allTasksComplete returns "true" if all background tasks are complete, "false" if tasks are still running
+// allTasksComplete returns "true" if all background tasks are complete or cancelled, "false" if tasks are still running
 func (ctx *ExecutionContext) allTasksComplete() string {
 	ctx.BackgroundMutex.RLock()
 	defer ctx.BackgroundMutex.RUnlock()
@@ -567,7 +799,7 @@ func (ctx *ExecutionContext) allTasksComplete() string {
 	}
 
 	for _, task := range ctx.BackgroundTasks {
-		if task.Status != TaskComplete {
+		if task.Status != TaskComplete && task.Status != TaskCancelled {
 			return "false"
 		}
 	}
@@ -621,6 +853,14 @@ func (ctx *ExecutionContext) updateLoopDuration() {
 	}
 }
 
+// currentLoop returns the innermost active loop context, or nil outside of loops
+func (ctx *ExecutionContext) currentLoop() *LoopContext {
+	if ctx.CurrentLoopIdx >= 0 && ctx.CurrentLoopIdx < len(ctx.LoopStack) {
+		return ctx.LoopStack[ctx.CurrentLoopIdx]
+	}
+	return nil
+}
+
 // getCurrentLoopDuration gets the duration of the current loop
 func (ctx *ExecutionContext) getCurrentLoopDuration() time.Duration {
 	if ctx.CurrentLoopIdx >= 0 && ctx.CurrentLoopIdx < len(ctx.LoopStack) {
@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// recipeIndexVersion is stored in the index file. An index written with another version is rebuilt.
+const recipeIndexVersion = 2
+
+// recipeSources are the recipe sources in their default order
+var recipeSources = []string{"local", "user", "public"}
+
+// recipeSummary describes an indexed recipe: enough to list, find and select it without parsing its file
+type recipeSummary struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Help        string   `json:"help,omitempty"`
+	Commands    []string `json:"commands,omitempty"`
+	Position    int      `json:"position"`
+	Path        string   `json:"-"`
+	Source      string   `json:"-"`
+}
+
+// indexedFile is the index entry of a recipe file. It stays valid while the file's size and modification time are
+// unchanged.
+type indexedFile struct {
+	ModTime       int64           `json:"mod_time"`
+	Size          int64           `json:"size"`
+	Recipes       []recipeSummary `json:"recipes,omitempty"`
+	HasComponents bool            `json:"has_components,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// recipeIndex is the on-disk cache of recipe files, keyed by absolute file path
+type recipeIndex struct {
+	Version int                    `json:"version"`
+	Files   map[string]indexedFile `json:"files"`
+}
+
+// recipeCatalog holds the indexed recipes and component files of every source
+type recipeCatalog struct {
+	recipes    map[string][]recipeSummary
+	components map[string][]string
+}
+
+// catalogWarnings receives the warnings about recipe files that fail to load
+var catalogWarnings io.Writer = os.Stdout
+
+var (
+	catalogMu  sync.Mutex
+	catalog    *recipeCatalog
+	catalogKey string
+)
+
+// getRecipeIndexPath returns the file holding the recipe index
+func getRecipeIndexPath() string {
+	return filepath.Join(getXDGCacheHome(), "shef", "recipe-index.json")
+}
+
+// getRecipeCatalog returns the recipe catalog, discovering and indexing recipe files on first use. The catalog is
+// rebuilt when the working directory or the recipe directories change.
+func getRecipeCatalog() *recipeCatalog {
+	cwd, _ := os.Getwd()
+	home, _ := os.UserHomeDir()
+	key := strings.Join([]string{cwd, home, getXDGConfigHome(), getXDGDataHome()}, "\x00")
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if catalog == nil || catalogKey != key {
+		catalog = buildRecipeCatalog()
+		catalogKey = key
+	}
+	return catalog
+}
+
+// buildRecipeCatalog walks every recipe source once, parsing only the files that are new or changed since the index
+// was written
+func buildRecipeCatalog() *recipeCatalog {
+	index := loadRecipeIndex()
+	files := make(map[string]indexedFile)
+	changed := false
+
+	c := &recipeCatalog{
+		recipes:    make(map[string][]recipeSummary),
+		components: make(map[string][]string),
+	}
+
+	for _, source := range recipeSources {
+		sources, _ := findRecipeSourcesByType(source == "local", source == "user", source == "public")
+		for _, path := range sources {
+			key, err := filepath.Abs(path)
+			if err != nil {
+				key = path
+			}
+
+			entry, seen := files[key]
+			if !seen {
+				var updated bool
+				entry, updated = index.entry(key)
+				changed = changed || updated
+				files[key] = entry
+			}
+
+			if entry.Error != "" {
+				fmt.Fprintf(catalogWarnings, "Warning: Failed to load recipes from %s: %s\n", path, entry.Error)
+				continue
+			}
+			for _, recipe := range entry.Recipes {
+				recipe.Path = path
+				recipe.Source = source
+				c.recipes[source] = append(c.recipes[source], recipe)
+			}
+			if entry.HasComponents {
+				c.components[source] = append(c.components[source], path)
+			}
+		}
+	}
+
+	if changed || len(files) != len(index.Files) {
+		index.Files = files
+		if err := index.save(); err != nil {
+			LogError("Failed to save recipe index", err, map[string]interface{}{"path": getRecipeIndexPath()})
+		}
+	}
+
+	Log(CategoryFileSystem, fmt.Sprintf("Indexed %d recipe files", len(files)))
+	return c
+}
+
+// loadRecipeIndex reads the recipe index from disk. A missing, unreadable or outdated index starts empty.
+func loadRecipeIndex() *recipeIndex {
+	index := &recipeIndex{Version: recipeIndexVersion, Files: make(map[string]indexedFile)}
+
+	data, err := os.ReadFile(getRecipeIndexPath())
+	if err != nil {
+		return index
+	}
+
+	var stored recipeIndex
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != recipeIndexVersion || stored.Files == nil {
+		Log(CategoryFileSystem, "Rebuilding recipe index")
+		return index
+	}
+	return &stored
+}
+
+// entry returns the index entry of a file, re-indexing it when it changed. It reports whether the entry was updated.
+func (idx *recipeIndex) entry(path string) (indexedFile, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return indexedFile{Error: err.Error()}, true
+	}
+
+	if cached, ok := idx.Files[path]; ok && cached.ModTime == info.ModTime().UnixNano() && cached.Size == info.Size() {
+		return cached, false
+	}
+
+	entry := indexRecipeFile(path)
+	entry.ModTime = info.ModTime().UnixNano()
+	entry.Size = info.Size()
+	return entry, true
+}
+
+// save writes the index to disk, replacing the file atomically so concurrent runs never read a partial index
+func (idx *recipeIndex) save() error {
+	path := getRecipeIndexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".recipe-index-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// indexRecipeFile parses a recipe file into its index entry
+func indexRecipeFile(path string) indexedFile {
+	file, err := loadFile(path)
+	if err != nil {
+		return indexedFile{Error: err.Error()}
+	}
+
+	entry := indexedFile{Recipes: make([]recipeSummary, len(file.Recipes))}
+	for i, recipe := range file.Recipes {
+		entry.Recipes[i] = recipeSummary{
+			Name:        recipe.Name,
+			Description: recipe.Description,
+			Category:    recipe.Category,
+			Author:      recipe.Author,
+			Help:        recipe.Help,
+			Commands:    operationCommands(recipe.Operations),
+			Position:    i,
+		}
+	}
+	for _, component := range file.Components {
+		if component.ID != "" {
+			entry.HasComponents = true
+			break
+		}
+	}
+	return entry
+}
+
+// operationCommands returns the command text of operations, including nested operations, so recipes can be searched
+// by what they run
+func operationCommands(operations []Operation) []string {
+	var commands []string
+	for _, op := range operations {
+		if command := strings.TrimSpace(op.Command); command != "" {
+			commands = append(commands, command)
+		}
+		commands = append(commands, operationCommands(op.Operations)...)
+	}
+	return commands
+}
+
+// sourceRecipes returns the recipes of one source, limited to a category when one is given
+func (c *recipeCatalog) sourceRecipes(source, category string) []recipeSummary {
+	if category == "" {
+		return c.recipes[source]
+	}
+
+	var recipes []recipeSummary
+	for _, recipe := range c.recipes[source] {
+		if strings.EqualFold(recipe.Category, category) {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes
+}
+
+// uniqueRecipes returns the recipes of the given sources in priority order, keeping the first recipe of each name
+func (c *recipeCatalog) uniqueRecipes(sources []string, category string) []recipeSummary {
+	var recipes []recipeSummary
+	seen := make(map[string]bool)
+
+	for _, source := range sources {
+		for _, recipe := range c.sourceRecipes(source, category) {
+			if !seen[recipe.Name] {
+				seen[recipe.Name] = true
+				recipes = append(recipes, recipe)
+			}
+		}
+	}
+	return recipes
+}
+
+// componentFiles returns the files of the given sources that define components
+func (c *recipeCatalog) componentFiles(sources []string) []string {
+	var files []string
+	for _, source := range sources {
+		files = append(files, c.components[source]...)
+	}
+	return files
+}
+
+// load parses the file of an indexed recipe and returns the full recipe
+func (s recipeSummary) load() (*Recipe, error) {
+	file, err := loadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recipe %s from %s: %w", s.Name, s.Path, err)
+	}
+
+	if s.Position < len(file.Recipes) && file.Recipes[s.Position].Name == s.Name {
+		recipe := file.Recipes[s.Position]
+		return &recipe, nil
+	}
+	return findRecipeByName(file.Recipes, s.Name)
+}
+
+// findRecipeSummary looks for an indexed recipe with a case-insensitive name match
+func findRecipeSummary(recipes []recipeSummary, name string) (recipeSummary, bool) {
+	for _, recipe := range recipes {
+		if strings.EqualFold(recipe.Name, name) {
+			return recipe, true
+		}
+	}
+	return recipeSummary{}, false
+}
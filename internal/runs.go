@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxRetainedRuns is the number of run directories kept under the runs root
+const maxRetainedRuns = 20
+
+// runPIDFile records the process that owns a run directory, so directories of live runs are never pruned
+const runPIDFile = "shef.pid"
+
+// getRunsRoot returns the directory that holds per-run data such as background task logs
+func getRunsRoot() string {
+	return filepath.Join(getXDGDataHome(), "shef", "runs")
+}
+
+// ensureRunDir creates the directory for the current recipe run on first use
+func (ctx *ExecutionContext) ensureRunDir() (string, error) {
+	ctx.BackgroundMutex.Lock()
+	defer ctx.BackgroundMutex.Unlock()
+
+	if ctx.RunDir != "" {
+		return ctx.RunDir, nil
+	}
+
+	runDir := filepath.Join(getRunsRoot(), ctx.RunID)
+	if err := os.MkdirAll(filepath.Join(runDir, "tasks"), 0755); err != nil {
+		return "", fmt.Errorf("failed to create run directory %s: %w", runDir, err)
+	}
+
+	pidPath := filepath.Join(runDir, runPIDFile)
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		LogError("Failed to write run PID file", err, map[string]interface{}{"path": pidPath})
+	}
+
+	Log(CategoryFileSystem, fmt.Sprintf("Created run directory: %s", runDir))
+	ctx.RunDir = runDir
+	pruneRunDirectories(getRunsRoot(), maxRetainedRuns)
+
+	return runDir, nil
+}
+
+// pruneRunDirectories removes the oldest run directories beyond the retention limit. Directories of runs that are
+// still alive are kept, and the others are ranked by their most recently written file, since a directory's own
+// modification time does not change while a task writes its log.
+func pruneRunDirectories(root string, keep int) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	type runEntry struct {
+		path    string
+		modTime int64
+	}
+
+	var runs []runEntry
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(root, entry.Name())
+		if isLiveRunDirectory(path) {
+			continue
+		}
+		runs = append(runs, runEntry{path: path, modTime: newestModTime(path)})
+	}
+
+	if len(runs) <= keep {
+		return
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].modTime > runs[j].modTime
+	})
+
+	for _, run := range runs[keep:] {
+		if err := os.RemoveAll(run.path); err != nil {
+			LogError("Failed to prune run directory", err, map[string]interface{}{"path": run.path})
+		}
+	}
+}
+
+// isLiveRunDirectory reports whether the process that created a run directory is still running
+func isLiveRunDirectory(path string) bool {
+	data, err := os.ReadFile(filepath.Join(path, runPIDFile))
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return false
+	}
+	return isProcessRunning(pid)
+}
+
+// newestModTime returns the latest modification time of a directory and everything inside it
+func newestModTime(root string) int64 {
+	var newest int64
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().UnixNano() > newest {
+			newest = info.ModTime().UnixNano()
+		}
+		return nil
+	})
+	return newest
+}
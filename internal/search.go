@@ -0,0 +1,269 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/agnivade/levenshtein"
+	"github.com/urfave/cli/v2"
+)
+
+// searchField is a recipe field that search terms are matched against. Matches in longer, less specific fields are
+// worth less: their scores are divided by the field's divisor.
+type searchField struct {
+	Name    string
+	Divisor int
+	Text    func(recipe recipeSummary) string
+}
+
+// searchFields are the searched recipe fields, most specific first
+var searchFields = []searchField{
+	{Name: "name", Divisor: 1, Text: func(r recipeSummary) string { return r.Name }},
+	{Name: "category", Divisor: 1, Text: func(r recipeSummary) string { return r.Category }},
+	{Name: "description", Divisor: 2, Text: func(r recipeSummary) string { return r.Description }},
+	{Name: "author", Divisor: 2, Text: func(r recipeSummary) string { return r.Author }},
+	{Name: "help", Divisor: 3, Text: func(r recipeSummary) string { return r.Help }},
+	{Name: "command", Divisor: 4, Text: func(r recipeSummary) string { return strings.Join(r.Commands, "\n") }},
+}
+
+// searchResult is a recipe that matched every search term
+type searchResult struct {
+	Recipe  recipeSummary
+	Score   int
+	Matches []string
+}
+
+// searchResultInfo represents a search result for JSON output
+type searchResultInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Source      string   `json:"source"`
+	Score       int      `json:"score"`
+	Matches     []string `json:"matches"`
+}
+
+// handleSearchCommand searches recipes of every source and lists the matches, best first
+func handleSearchCommand(c *cli.Context, sourcePriority []string) error {
+	terms := c.Args().Slice()
+	interactive := c.Bool("interactive")
+	if len(terms) == 0 && !interactive {
+		return fmt.Errorf("you must specify search terms")
+	}
+
+	recipes := collectRecipes(sourcePriority, determineSourceFlags(c), c.String("category"))
+	results := searchRecipes(recipes, strings.Join(terms, " "))
+
+	if interactive {
+		return pickSearchResult(c, results, sourcePriority)
+	}
+
+	if len(results) == 0 {
+		return handleEmptyResults(c)
+	}
+
+	if c.Bool("json") {
+		return outputSearchResultsAsJSON(results)
+	}
+
+	listSearchResults(results)
+	return nil
+}
+
+// searchRecipes returns the recipes matching every term of the query, best matches first. Ties are ordered by name.
+// An empty query returns every recipe ordered by name.
+func searchRecipes(recipes []recipeSummary, query string) []searchResult {
+	terms := strings.Fields(query)
+	var results []searchResult
+
+	for _, recipe := range recipes {
+		result := searchResult{Recipe: recipe}
+		matchedFields := make(map[string]bool)
+		matched := true
+
+		for _, term := range terms {
+			score, fields := scoreSearchTerm(term, recipe)
+			if len(fields) == 0 {
+				matched = false
+				break
+			}
+			result.Score += score
+			for _, field := range fields {
+				if !matchedFields[field] {
+					matchedFields[field] = true
+					result.Matches = append(result.Matches, field)
+				}
+			}
+		}
+
+		if matched {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Recipe.Name < results[j].Recipe.Name
+	})
+
+	return results
+}
+
+// scoreSearchTerm scores a single term against a recipe. The term's score is its best field score; every field it
+// matched is returned. A recipe name also matches words within a small edit distance, so typos still find it.
+func scoreSearchTerm(term string, recipe recipeSummary) (int, []string) {
+	best := 0
+	var fields []string
+
+	for _, field := range searchFields {
+		score, ok := scoreSearchField(term, field.Text(recipe), field.Name == "name")
+		if !ok {
+			continue
+		}
+		fields = append(fields, field.Name)
+		if score /= field.Divisor; score > best {
+			best = score
+		}
+	}
+
+	if len(fields) == 0 && isNameTypo(term, recipe.Name) {
+		return len([]rune(term)) * fuzzyScoreMatch / 2, []string{"name"}
+	}
+	return best, fields
+}
+
+// scoreSearchField fuzzy matches a term within a field's text. Outside the name a match must be mostly contiguous,
+// since a short term is found scattered through almost any long text.
+func scoreSearchField(term, text string, loose bool) (int, bool) {
+	if text == "" {
+		return 0, false
+	}
+
+	score, _, ok := fuzzyMatch(term, text)
+	if !ok || (!loose && score < len([]rune(term))*fuzzyScoreMatch) {
+		return 0, false
+	}
+	return score, true
+}
+
+// isNameTypo reports whether a term is within a small edit distance of the recipe name or one of its words
+func isNameTypo(term, name string) bool {
+	length := len([]rune(term))
+	if length < 4 {
+		return false
+	}
+
+	maxDistance := 1
+	if length > 5 {
+		maxDistance = 2
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range append(words, name) {
+		if levenshtein.ComputeDistance(strings.ToLower(term), strings.ToLower(word)) <= maxDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// listSearchResults displays search results in rank order
+func listSearchResults(results []searchResult) {
+	fmt.Println("\nMatching recipes:")
+	fmt.Println()
+
+	for _, result := range results {
+		recipe := result.Recipe
+		category := ""
+		if recipe.Category != "" {
+			category = fmt.Sprintf(" %s%s%s",
+				FormatText("[", ColorNone, StyleDim),
+				FormatText(strings.ToLower(recipe.Category), ColorMagenta, StyleNone),
+				FormatText("]", ColorNone, StyleDim),
+			)
+		}
+
+		fmt.Printf(
+			"    %s %s%s: %s %s\n",
+			FormatText("•", ColorNone, StyleDim),
+			FormatText(strings.ToLower(recipe.Name), ColorGreen, StyleBold),
+			category,
+			strings.ToLower(recipe.Description),
+			FormatText("("+strings.Join(result.Matches, ", ")+")", ColorNone, StyleDim),
+		)
+	}
+
+	fmt.Printf("\n\n")
+}
+
+// outputSearchResultsAsJSON formats and outputs search results as JSON
+func outputSearchResultsAsJSON(results []searchResult) error {
+	info := make([]searchResultInfo, len(results))
+	for i, result := range results {
+		info[i] = searchResultInfo{
+			Name:        result.Recipe.Name,
+			Description: result.Recipe.Description,
+			Category:    result.Recipe.Category,
+			Author:      result.Recipe.Author,
+			Source:      result.Recipe.Source,
+			Score:       result.Score,
+			Matches:     result.Matches,
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(jsonBytes))
+	return nil
+}
+
+// pickSearchResult lets the user narrow the search results with the fuzzy finder and runs the chosen recipe
+func pickSearchResult(c *cli.Context, results []searchResult, sourcePriority []string) error {
+	if len(results) == 0 {
+		return fmt.Errorf("no recipes match the search terms")
+	}
+
+	options := make([]string, len(results))
+	descriptions := make(map[string]string, len(results))
+	for i, result := range results {
+		options[i] = result.Recipe.Name
+		descriptions[result.Recipe.Name] = searchResultDescription(result.Recipe)
+	}
+
+	prompt := &FuzzySelect{
+		Message:      "Choose a recipe to run:",
+		Options:      options,
+		Descriptions: descriptions,
+		PageSize:     fuzzyPageSize,
+	}
+
+	var selected string
+	if err := survey.AskOne(prompt, &selected); err != nil {
+		return err
+	}
+
+	debugger := setupDebugging(c)
+	defer debugger()
+
+	return dispatch(c, []string{selected}, sourcePriority)
+}
+
+// searchResultDescription describes a recipe in the pick list by its category and description
+func searchResultDescription(recipe recipeSummary) string {
+	if recipe.Category == "" {
+		return recipe.Description
+	}
+	return fmt.Sprintf("[%s] %s", strings.ToLower(recipe.Category), recipe.Description)
+}
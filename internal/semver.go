@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// semanticVersion is a parsed MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] version
+type semanticVersion struct {
+	Prefix     string
+	Major      int64
+	Minor      int64
+	Patch      int64
+	Prerelease string
+	Build      string
+}
+
+var semverPattern = regexp.MustCompile(`^([vV]?)(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// String renders the version in its canonical form, keeping any v prefix
+func (v semanticVersion) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// parseSemver parses a version string such as v1.4.2, 1.4 or 2.0.0-rc.1+build.5
+func parseSemver(value interface{}) (semanticVersion, error) {
+	if v, ok := value.(semanticVersion); ok {
+		return v, nil
+	}
+
+	s := strings.TrimSpace(fmt.Sprintf("%v", value))
+	matches := semverPattern.FindStringSubmatch(s)
+	if matches == nil {
+		return semanticVersion{}, fmt.Errorf("invalid semantic version: %q", s)
+	}
+
+	part := func(s string) int64 {
+		if s == "" {
+			return 0
+		}
+		n, _ := strconv.ParseInt(s, 10, 64)
+		return n
+	}
+
+	return semanticVersion{
+		Prefix:     matches[1],
+		Major:      part(matches[2]),
+		Minor:      part(matches[3]),
+		Patch:      part(matches[4]),
+		Prerelease: matches[5],
+		Build:      matches[6],
+	}, nil
+}
+
+// compareSemver orders two versions following semver precedence rules, ignoring build metadata
+func compareSemver(a, b semanticVersion) int {
+	for _, pair := range [][2]int64{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+
+	aParts := strings.Split(a.Prerelease, ".")
+	bParts := strings.Split(b.Prerelease, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if cmp := comparePrereleaseIdentifier(aParts[i], bParts[i]); cmp != 0 {
+			return cmp
+		}
+	}
+
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrereleaseIdentifier compares numeric identifiers numerically and ranks them below alphanumeric ones
+func comparePrereleaseIdentifier(a, b string) int {
+	aNum, aErr := strconv.ParseInt(a, 10, 64)
+	bNum, bErr := strconv.ParseInt(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case aNum < bNum:
+			return -1
+		case aNum > bNum:
+			return 1
+		default:
+			return 0
+		}
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// bumpSemver increments a version. Part is major, minor, patch, prerelease or prerelease:<id>.
+func bumpSemver(part string, v semanticVersion) (semanticVersion, error) {
+	v.Build = ""
+	part, preid, _ := strings.Cut(part, ":")
+
+	switch part {
+	case "major":
+		if v.Prerelease == "" || v.Minor != 0 || v.Patch != 0 {
+			v.Major++
+		}
+		v.Minor, v.Patch, v.Prerelease = 0, 0, ""
+	case "minor":
+		if v.Prerelease == "" || v.Patch != 0 {
+			v.Minor++
+		}
+		v.Patch, v.Prerelease = 0, ""
+	case "patch":
+		if v.Prerelease == "" {
+			v.Patch++
+		}
+		v.Prerelease = ""
+	case "prerelease":
+		if v.Prerelease == "" {
+			v.Patch++
+		}
+		v.Prerelease = bumpPrerelease(v, preid)
+	default:
+		return v, fmt.Errorf("invalid semver part %q (expected major, minor, patch or prerelease)", part)
+	}
+
+	return v, nil
+}
+
+// bumpPrerelease returns the next prerelease identifier, starting a new rc.0 series on a released version
+func bumpPrerelease(v semanticVersion, preid string) string {
+	if v.Prerelease == "" {
+		if preid == "" {
+			preid = "rc"
+		}
+		return preid + ".0"
+	}
+
+	parts := strings.Split(v.Prerelease, ".")
+	if preid != "" && parts[0] != preid {
+		return preid + ".0"
+	}
+
+	last := parts[len(parts)-1]
+	if n, err := strconv.ParseInt(last, 10, 64); err == nil {
+		parts[len(parts)-1] = strconv.FormatInt(n+1, 10)
+		return strings.Join(parts, ".")
+	}
+	return v.Prerelease + ".0"
+}
+
+// semverFunctions adds semantic version functions to the template function map
+func semverFunctions(funcs template.FuncMap) {
+	funcs["semverParse"] = func(value interface{}) (semanticVersion, error) {
+		return parseSemver(value)
+	}
+	funcs["semverBump"] = func(part string, value interface{}) (string, error) {
+		v, err := parseSemver(value)
+		if err != nil {
+			return "", err
+		}
+		bumped, err := bumpSemver(part, v)
+		if err != nil {
+			return "", err
+		}
+		return bumped.String(), nil
+	}
+	funcs["semverCompare"] = func(a, b interface{}) (int, error) {
+		av, err := parseSemver(a)
+		if err != nil {
+			return 0, err
+		}
+		bv, err := parseSemver(b)
+		if err != nil {
+			return 0, err
+		}
+		return compareSemver(av, bv), nil
+	}
+	funcs["semverSort"] = semverSort
+}
+
+// semverSort sorts versions in ascending order, dropping entries that are not valid versions
+func semverSort(input interface{}) []string {
+	var versions []semanticVersion
+	var originals []string
+	for _, item := range toList(input) {
+		v, err := parseSemver(item)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+		originals = append(originals, item)
+	}
+
+	indexes := make([]int, len(versions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return compareSemver(versions[indexes[i]], versions[indexes[j]]) < 0
+	})
+
+	sorted := make([]string, len(indexes))
+	for i, index := range indexes {
+		sorted[i] = originals[index]
+	}
+	return sorted
+}
+
+// versionConditionFunctions adds semantic version functions to the condition function map
+func versionConditionFunctions(funcs map[string]conditionFunc) {
+	funcs["semver"] = func(ctx *ExecutionContext, args []interface{}) (interface{}, error) {
+		if err := expectConditionArgs("semver", args, 1); err != nil {
+			return nil, err
+		}
+		return parseSemver(args[0])
+	}
+}
+
+// compareSemverOperands compares two condition operands as versions when either one came from semver()
+func compareSemverOperands(left, right interface{}) (int, bool) {
+	_, leftIsVersion := left.(semanticVersion)
+	_, rightIsVersion := right.(semanticVersion)
+	if !leftIsVersion && !rightIsVersion {
+		return 0, false
+	}
+
+	leftVersion, err := parseSemver(left)
+	if err != nil {
+		return 0, false
+	}
+	rightVersion, err := parseSemver(right)
+	if err != nil {
+		return 0, false
+	}
+	return compareSemver(leftVersion, rightVersion), true
+}
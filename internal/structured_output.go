@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Structured output formats parse command output into values templates can index
+const (
+	OutputFormatJSON = "json"
+	OutputFormatYAML = "yaml"
+	OutputFormatCSV  = "csv"
+	OutputFormatKV   = "kv"
+)
+
+// isStructuredOutputFormat reports whether an output format produces a structured value
+func isStructuredOutputFormat(format string) bool {
+	switch format {
+	case OutputFormatJSON, OutputFormatYAML, OutputFormatCSV, OutputFormatKV:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseStructuredOutput decodes command output according to a structured output format
+func parseStructuredOutput(output string, format string) (interface{}, error) {
+	trimmed := strings.TrimSpace(output)
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	switch format {
+	case OutputFormatJSON:
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid json output: %w", err)
+		}
+		return normalizeJSONNumbers(value), nil
+
+	case OutputFormatYAML:
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(trimmed), &value); err != nil {
+			return nil, fmt.Errorf("invalid yaml output: %w", err)
+		}
+		return value, nil
+
+	case OutputFormatCSV:
+		return parseCSVOutput(trimmed)
+
+	case OutputFormatKV:
+		return parseKeyValueOutput(trimmed), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported structured output format: %s", format)
+	}
+}
+
+// parseCSVOutput turns CSV output into a list of rows keyed by the header columns
+func parseCSVOutput(output string) ([]interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv output: %w", err)
+	}
+
+	if len(records) == 0 {
+		return []interface{}{}, nil
+	}
+
+	header := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			} else {
+				row[column] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+var kvLinePattern = regexp.MustCompile(`^([^=:]+?)\s*(?:=|:)\s*(.*)$`)
+
+// parseKeyValueOutput turns key=value or key: value lines into a map
+func parseKeyValueOutput(output string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		matches := kvLinePattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		values[strings.TrimSpace(matches[1])] = strings.Trim(strings.TrimSpace(matches[2]), `"'`)
+	}
+	return values
+}
+
+// storeStructuredOutput parses an operation's output and records the structured value
+func storeStructuredOutput(op Operation, output string, ctx *ExecutionContext) error {
+	if op.ID == "" || !isStructuredOutputFormat(op.OutputFormat) {
+		return nil
+	}
+
+	value, err := parseStructuredOutput(output, op.OutputFormat)
+	if err != nil {
+		return fmt.Errorf("failed to parse output of operation %s: %w", op.ID, err)
+	}
+
+	ctx.OperationMutex.Lock()
+	if ctx.OperationData == nil {
+		ctx.OperationData = make(map[string]interface{})
+	}
+	if value == nil {
+		delete(ctx.OperationData, op.ID)
+	} else {
+		ctx.OperationData[op.ID] = value
+	}
+	ctx.OperationMutex.Unlock()
+	ctx.invalidateTemplateVars()
+
+	if value == nil {
+		return nil
+	}
+	Log(CategoryOperation, fmt.Sprintf("Stored %s output for operation %s", op.OutputFormat, op.ID))
+	return nil
+}
+
+// collectionItems resolves a foreach collection to a list of items, keeping structured values intact
+func collectionItems(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case map[string]interface{}:
+		return mapEntries(v), true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return items, true
+	}
+
+	return nil, false
+}
+
+// mapEntries converts a map into key/value entries sorted by key
+func mapEntries(values map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, map[string]interface{}{"key": key, "value": values[key]})
+	}
+	return entries
+}
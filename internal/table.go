@@ -21,21 +21,8 @@ func renderTableFromJSON(jsonData string) string {
 	t := table.NewWriter()
 	t.SetOutputMirror(io.Discard)
 
-	style := table.StyleRounded
-	if val, ok := tableData["style"].(string); ok {
-		switch strings.ToLower(val) {
-		case "light":
-			style = table.StyleLight
-		case "double":
-			style = table.StyleDouble
-		case "bold":
-			style = table.StyleBold
-		case "ascii":
-			style = table.StyleDefault
-		}
-	}
-
-	t.SetStyle(style)
+	style, _ := tableData["style"].(string)
+	t.SetStyle(tableStyleByName(style))
 
 	if headers, ok := tableData["headers"].([]interface{}); ok {
 		headerRow := table.Row{}
@@ -103,24 +90,28 @@ func renderTableFromJSON(jsonData string) string {
 	return t.Render()
 }
 
-// renderSimpleTable is a helper for quick tables with minimal configuration
-func renderSimpleTable(headers []string, rows [][]string, style string) string {
-	t := table.NewWriter()
-	t.SetOutputMirror(io.Discard)
-
-	tableStyle := table.StyleRounded
-	switch strings.ToLower(style) {
+// tableStyleByName returns the table style for a style name, defaulting to rounded
+func tableStyleByName(name string) table.Style {
+	switch strings.ToLower(name) {
 	case "light":
-		tableStyle = table.StyleLight
+		return table.StyleLight
 	case "double":
-		tableStyle = table.StyleDouble
+		return table.StyleDouble
 	case "bold":
-		tableStyle = table.StyleBold
+		return table.StyleBold
 	case "ascii":
-		tableStyle = table.StyleDefault
+		return table.StyleDefault
+	default:
+		return table.StyleRounded
 	}
+}
 
-	t.SetStyle(tableStyle)
+// renderSimpleTable is a helper for quick tables with minimal configuration
+func renderSimpleTable(headers []string, rows [][]string, style string) string {
+	t := table.NewWriter()
+	t.SetOutputMirror(io.Discard)
+
+	t.SetStyle(tableStyleByName(style))
 
 	if len(headers) > 0 {
 		headerRow := table.Row{}
@@ -165,18 +156,7 @@ func TableFuncMap() map[string]interface{} {
 		t := table.NewWriter()
 		t.SetOutputMirror(io.Discard)
 
-		tableStyle := table.StyleRounded
-		switch strings.ToLower(styleStr) {
-		case "light":
-			tableStyle = table.StyleLight
-		case "double":
-			tableStyle = table.StyleDouble
-		case "bold":
-			tableStyle = table.StyleBold
-		case "ascii":
-			tableStyle = table.StyleDefault
-		}
-		t.SetStyle(tableStyle)
+		t.SetStyle(tableStyleByName(styleStr))
 
 		if len(headerList) > 0 {
 			headerRow := table.Row{}
@@ -0,0 +1,377 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dataFunctions adds structured data functions to the template function map
+func dataFunctions(funcs template.FuncMap) {
+	funcs["fromJson"] = fromJSON
+	funcs["toJson"] = toJSON
+	funcs["toPrettyJson"] = toPrettyJSON
+	funcs["fromYaml"] = fromYAML
+	funcs["toYaml"] = toYAML
+	funcs["jsonPath"] = jsonPath
+	funcs["keys"] = mapKeys
+	funcs["values"] = mapValues
+	funcs["pluck"] = pluckField
+	funcs["sortBy"] = sortByField
+	funcs["uniq"] = uniqueItems
+	funcs["groupBy"] = groupByField
+}
+
+// fromJSON decodes a JSON string, passing already decoded values through unchanged
+func fromJSON(input interface{}) (interface{}, error) {
+	s, ok := input.(string)
+	if !ok {
+		return input, nil
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("fromJson: %w", err)
+	}
+	return normalizeJSONNumbers(value), nil
+}
+
+// toJSON encodes a value as compact JSON
+func toJSON(value interface{}) (string, error) {
+	return encodeJSON(value, "")
+}
+
+// toPrettyJSON encodes a value as indented JSON
+func toPrettyJSON(value interface{}) (string, error) {
+	return encodeJSON(value, "  ")
+}
+
+// encodeJSON encodes a value without escaping HTML characters
+func encodeJSON(value interface{}, indent string) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// fromYAML decodes a YAML string, passing already decoded values through unchanged
+func fromYAML(input interface{}) (interface{}, error) {
+	s, ok := input.(string)
+	if !ok {
+		return input, nil
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(s), &value); err != nil {
+		return nil, fmt.Errorf("fromYaml: %w", err)
+	}
+	return value, nil
+}
+
+// toYAML encodes a value as YAML
+func toYAML(value interface{}) (string, error) {
+	data, err := yaml.Marshal(normalizeJSONNumbers(value))
+	if err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// normalizeJSONNumbers converts decoded JSON numbers to native numbers so they encode as numbers in YAML
+func normalizeJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = normalizeJSONNumbers(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeJSONNumbers(item)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+// toStructured decodes JSON strings so data functions accept both raw output and parsed values
+func toStructured(input interface{}) interface{} {
+	if s, ok := input.(string); ok {
+		if value, err := fromJSON(s); err == nil && value != nil {
+			return value
+		}
+	}
+	return input
+}
+
+// jsonPath queries data with a jq-style path such as .items[0].name or .items[].name
+func jsonPath(input interface{}, path string) (interface{}, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{toStructured(input)}
+	fanOut := false
+
+	for _, segment := range segments {
+		var next []interface{}
+		for _, value := range current {
+			switch {
+			case segment.iterate:
+				fanOut = true
+				next = append(next, iterableValues(value)...)
+			case segment.isIndex:
+				items := iterableValues(value)
+				index := segment.index
+				if index < 0 {
+					index += len(items)
+				}
+				if _, isMap := value.(map[string]interface{}); !isMap && index >= 0 && index < len(items) {
+					next = append(next, items[index])
+				} else if !fanOut {
+					next = append(next, nil)
+				}
+			default:
+				if m, ok := value.(map[string]interface{}); ok {
+					next = append(next, m[segment.key])
+				} else if !fanOut {
+					next = append(next, nil)
+				}
+			}
+		}
+		current = next
+	}
+
+	if fanOut {
+		if current == nil {
+			return []interface{}{}, nil
+		}
+		return current, nil
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+	return current[0], nil
+}
+
+// jsonPathSegment is a single step of a jsonPath query
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+// parseJSONPath splits a jq-style path into segments
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	var segments []jsonPathSegment
+	path = strings.TrimSpace(path)
+
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			if i > start {
+				segments = append(segments, jsonPathSegment{key: path[start:i]})
+			}
+
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonPath: missing ']' in %q", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "":
+				segments = append(segments, jsonPathSegment{iterate: true})
+			case strings.HasPrefix(inner, `"`) || strings.HasPrefix(inner, "'"):
+				segments = append(segments, jsonPathSegment{key: strings.Trim(inner, `"'`)})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("jsonPath: invalid index %q in %q", inner, path)
+				}
+				segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			}
+
+		default:
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			segments = append(segments, jsonPathSegment{key: path[start:i]})
+		}
+	}
+
+	return segments, nil
+}
+
+// iterableValues returns the elements of a list, or the values of a map ordered by key
+func iterableValues(value interface{}) []interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		return mapValues(m)
+	}
+
+	rv := reflect.ValueOf(value)
+	if value == nil || rv.Kind() != reflect.Slice {
+		return nil
+	}
+
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+// mapKeys returns the sorted keys of a map
+func mapKeys(input interface{}) []string {
+	m, ok := toStructured(input).(map[string]interface{})
+	if !ok {
+		return []string{}
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mapValues returns the values of a map ordered by key
+func mapValues(input interface{}) []interface{} {
+	m, ok := toStructured(input).(map[string]interface{})
+	if !ok {
+		return []interface{}{}
+	}
+
+	values := make([]interface{}, 0, len(m))
+	for _, key := range mapKeys(m) {
+		values = append(values, m[key])
+	}
+	return values
+}
+
+// pluckField collects a field from every object in a list
+func pluckField(input interface{}, field string) []interface{} {
+	items := iterableValues(toStructured(input))
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			if value, exists := m[field]; exists {
+				result = append(result, value)
+			}
+		}
+	}
+	return result
+}
+
+// sortByField sorts a list of objects by a field, numerically when both values are numbers
+func sortByField(input interface{}, field string) []interface{} {
+	items := append([]interface{}{}, iterableValues(toStructured(input))...)
+
+	fieldValue := func(item interface{}) interface{} {
+		if field == "" || field == "." {
+			return item
+		}
+		if m, ok := item.(map[string]interface{}); ok {
+			return m[field]
+		}
+		return nil
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return lessDataValue(fieldValue(items[i]), fieldValue(items[j]))
+	})
+	return items
+}
+
+// lessDataValue orders two values numerically when possible and as strings otherwise
+func lessDataValue(a, b interface{}) bool {
+	aNum, aErr := strconv.ParseFloat(fmt.Sprintf("%v", a), 64)
+	bNum, bErr := strconv.ParseFloat(fmt.Sprintf("%v", b), 64)
+	if aErr == nil && bErr == nil {
+		return aNum < bNum
+	}
+	return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
+}
+
+// uniqueItems removes duplicate values from a list while keeping the first occurrence
+func uniqueItems(input interface{}) []interface{} {
+	var items []interface{}
+	if s, ok := input.(string); ok && !strings.HasPrefix(strings.TrimSpace(s), "[") {
+		for _, line := range toList(s) {
+			items = append(items, line)
+		}
+	} else {
+		items = iterableValues(toStructured(input))
+	}
+
+	seen := make(map[string]bool)
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		key, err := toJSON(item)
+		if err != nil {
+			key = fmt.Sprintf("%v", item)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, item)
+	}
+	return result
+}
+
+// groupByField groups a list of objects into a map keyed by the value of a field
+func groupByField(input interface{}, field string) map[string]interface{} {
+	groups := make(map[string]interface{})
+	for _, item := range iterableValues(toStructured(input)) {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		key := fmt.Sprintf("%v", m[field])
+		group, _ := groups[key].([]interface{})
+		groups[key] = append(group, item)
+	}
+	return groups
+}
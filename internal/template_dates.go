@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// dateFunctions adds date and time functions to the template function map
+func dateFunctions(funcs template.FuncMap) {
+	funcs["now"] = time.Now
+	funcs["date"] = formatDate
+	funcs["unixEpoch"] = unixEpoch
+	funcs["dateAdd"] = dateAdd
+	funcs["dateDiff"] = dateDiff
+	funcs["parseDate"] = parseDate
+	funcs["humanizeDuration"] = func(value interface{}) (string, error) {
+		return humanizeDuration(value, false)
+	}
+	funcs["humanizeDurationMs"] = func(value interface{}) (string, error) {
+		return humanizeDuration(value, true)
+	}
+}
+
+// dateLayouts are the layouts tried, in order, when parsing a date without an explicit layout
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.RubyDate,
+}
+
+// strftimeDirectives maps strftime directives to Go layout fragments
+var strftimeDirectives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'j': "002",
+	'Z': "MST",
+	'z': "-0700",
+	'F': "2006-01-02",
+	'T': "15:04:05",
+}
+
+// strftimeLayout converts a strftime pattern to a Go reference layout. Formats without % are already layouts, and
+// directives without a layout equivalent are kept as written.
+func strftimeLayout(format string) string {
+	if !strings.Contains(format, "%") {
+		return format
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		if layout, ok := strftimeDirectives[format[i]]; ok {
+			sb.WriteString(layout)
+		} else if format[i] == '%' {
+			sb.WriteByte('%')
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(format[i])
+		}
+	}
+	return sb.String()
+}
+
+// formatDate formats a time, or the current time when none is given. Formats containing % are
+// treated as strftime patterns, anything else as a Go reference layout.
+func formatDate(format string, value ...interface{}) (string, error) {
+	t := time.Now()
+	if len(value) > 0 {
+		parsed, err := toTime(value[0])
+		if err != nil {
+			return "", err
+		}
+		t = parsed
+	}
+
+	if !strings.Contains(format, "%") {
+		return t.Format(format), nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			sb.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		switch directive := format[i]; directive {
+		case '%':
+			sb.WriteByte('%')
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+		default:
+			if layout, ok := strftimeDirectives[directive]; ok {
+				sb.WriteString(t.Format(layout))
+			} else {
+				sb.WriteByte('%')
+				sb.WriteByte(directive)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// unixEpoch returns the Unix timestamp in seconds of a time, or of the current time when none is given
+func unixEpoch(value ...interface{}) (int64, error) {
+	if len(value) == 0 {
+		return time.Now().Unix(), nil
+	}
+	t, err := toTime(value[0])
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+// dateAdd adds a duration such as 90m, -2h or 7d to a time
+func dateAdd(duration string, value interface{}) (time.Time, error) {
+	t, err := toTime(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	d, err := parseDurationWithDays(duration)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("dateAdd: invalid duration %q", duration)
+	}
+	return t.Add(d), nil
+}
+
+// dateDiff returns the duration from start to end
+func dateDiff(start, end interface{}) (time.Duration, error) {
+	startTime, err := toTime(start)
+	if err != nil {
+		return 0, err
+	}
+	endTime, err := toTime(end)
+	if err != nil {
+		return 0, err
+	}
+	return endTime.Sub(startTime), nil
+}
+
+// parseDate parses a date string using an optional layout, trying common layouts when none is given
+func parseDate(value string, layout ...string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(layout) > 0 {
+		t, err := time.ParseInLocation(layout[0], value, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parseDate: %w", err)
+		}
+		return t, nil
+	}
+
+	for _, candidate := range dateLayouts {
+		if t, err := time.ParseInLocation(candidate, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("parseDate: unrecognized date %q", value)
+}
+
+// toTime converts times, Unix timestamps and date strings to a time.Time
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case dateAnswer:
+		return v.Time, nil
+	case *time.Time:
+		return *v, nil
+	case int:
+		return time.Unix(int64(v), 0), nil
+	case int64:
+		return time.Unix(v, 0), nil
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	case json.Number:
+		return parseDate(v.String())
+	case string:
+		return parseDate(v)
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %v (%T) to a time", value, value)
+	}
+}
+
+// humanizeDuration formats a duration like the duration_fmt loop variables. Numbers are treated as seconds.
+func humanizeDuration(value interface{}, withMs bool) (string, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		d = v
+	case string:
+		parsed, err := parseDurationWithDays(v)
+		if err != nil {
+			seconds, numErr := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if numErr != nil {
+				return "", fmt.Errorf("humanizeDuration: invalid duration %q", v)
+			}
+			parsed = time.Duration(seconds * float64(time.Second))
+		}
+		d = parsed
+	default:
+		d = time.Duration(toFloat64(value) * float64(time.Second))
+	}
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	if withMs {
+		return sign + formatDurationWithMs(d), nil
+	}
+	return sign + formatDuration(d), nil
+}
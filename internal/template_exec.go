@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execOptions controls how the exec template functions run commands for the current operation
+type execOptions struct {
+	Operation string
+	Workdir   string
+	UserShell bool
+	Timeout   time.Duration
+}
+
+// templateExecError reports a command run from a template with execOrFail that failed
+type templateExecError struct {
+	Command   string
+	Operation string
+	Stderr    string
+	Err       error
+}
+
+func (e *templateExecError) Error() string {
+	msg := fmt.Sprintf("exec %q failed", e.Command)
+	if e.Operation != "" {
+		msg += fmt.Sprintf(" in operation '%s'", e.Operation)
+	}
+	msg += fmt.Sprintf(": %v", e.Err)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *templateExecError) Unwrap() error {
+	return e.Err
+}
+
+// setExecOptions installs the exec options for an operation and returns the previous ones so they can be restored
+func (ctx *ExecutionContext) setExecOptions(opts *execOptions) *execOptions {
+	return ctx.execOptions.Swap(opts)
+}
+
+// currentExecOptions returns the exec options of the operation being rendered, falling back to the recipe workdir
+func (ctx *ExecutionContext) currentExecOptions() execOptions {
+	if opts := ctx.execOptions.Load(); opts != nil {
+		return *opts
+	}
+
+	return execOptions{Workdir: ctx.recipeWorkdir()}
+}
+
+// recipeWorkdir returns the recipe-level working directory, or "" when none is set
+func (ctx *ExecutionContext) recipeWorkdir() string {
+	if workdir, ok := ctx.Vars["workdir"]; ok {
+		return fmt.Sprintf("%v", workdir)
+	}
+	return ""
+}
+
+// execCommand executes a shell command outside of a recipe and returns its output, or "" if it fails
+func execCommand(cmd string) string {
+	output, _ := runTemplateCommand(nil, cmd)
+	return output
+}
+
+// execOrFailCommand executes a shell command outside of a recipe and fails rendering if it fails
+func execOrFailCommand(cmd string) (string, error) {
+	return runTemplateCommand(nil, cmd)
+}
+
+// execFunc returns the exec template function bound to an execution context. Failures render as ""
+// but are recorded in the debug log.
+func execFunc(ctx *ExecutionContext) func(string) string {
+	return func(cmd string) string {
+		output, _ := runTemplateCommand(ctx, cmd)
+		return output
+	}
+}
+
+// execOrFailFunc returns the execOrFail template function bound to an execution context
+func execOrFailFunc(ctx *ExecutionContext) func(string) (string, error) {
+	return func(cmd string) (string, error) {
+		return runTemplateCommand(ctx, cmd)
+	}
+}
+
+// runTemplateCommand runs a command from a template using the current operation's workdir, shell and timeout
+func runTemplateCommand(ctx *ExecutionContext, cmdStr string) (string, error) {
+	var opts execOptions
+	dryRun := false
+	if ctx != nil {
+		opts = ctx.currentExecOptions()
+		dryRun = ctx.DryRun
+	}
+
+	metadata := map[string]interface{}{
+		"operation": opts.Operation,
+		"workdir":   opts.Workdir,
+	}
+	if opts.Timeout > 0 {
+		metadata["timeout"] = opts.Timeout.String()
+	}
+
+	if dryRun {
+		fmt.Printf("[dry-run] exec: %s\n", cmdStr)
+		Log(CategoryCommand, fmt.Sprintf("Skipped template exec (dry run): %s", cmdStr), metadata)
+		return "", nil
+	}
+
+	cmdCtx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(cmdCtx, opts.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(cmdCtx, ExecShell, "-c", prepShellCmd(cmdStr, opts.UserShell, false))
+	cmd.WaitDelay = time.Second
+	cmd.Dir = opts.Workdir
+	cmd.Env = os.Environ()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	metadata["duration"] = time.Since(start).String()
+
+	if err != nil {
+		if errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", opts.Timeout)
+		}
+		metadata["stderr"] = stderr.String()
+		LogError(fmt.Sprintf("Template exec failed: %s", cmdStr), err, metadata)
+
+		return "", &templateExecError{
+			Command:   cmdStr,
+			Operation: opts.Operation,
+			Stderr:    strings.TrimSpace(stderr.String()),
+			Err:       err,
+		}
+	}
+
+	LogCommand(fmt.Sprintf("Template exec: %s", cmdStr), metadata)
+	return stdout.String(), nil
+}
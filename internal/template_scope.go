@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// maxCachedTemplates bounds the parse cache so templates built from dynamic strings cannot grow it without limit
+const maxCachedTemplates = 1024
+
+// templateScope caches the variables passed to templates between mutations of the execution context.
+// The cached map is shared and must be treated as read-only; use withTemplateVars to add values to it.
+// Recipe variables set while the cache is fresh are kept in a pending layer and merged into a copy of the
+// cached map on the next read, so loop iterations do not rebuild every variable and operation output.
+type templateScope struct {
+	mu           sync.Mutex
+	version      uint64
+	builtVersion uint64
+	vars         map[string]interface{}
+	pending      map[string]interface{}
+	duration     time.Duration
+	allComplete  string
+	anyFailed    string
+}
+
+// templateCache holds parsed templates keyed by their source
+type templateCache struct {
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+}
+
+// defaultTemplateCache is used when rendering without an execution context
+var defaultTemplateCache = &templateCache{}
+
+// deletedVar marks a variable removed in the pending layer
+type deletedVar struct{}
+
+// setVar sets a recipe variable and records the change in the template scope
+func (ctx *ExecutionContext) setVar(name string, value interface{}) {
+	ctx.Vars[name] = value
+	ctx.overlayTemplateVar(name, value)
+}
+
+// deleteVar removes a recipe variable and records the change in the template scope
+func (ctx *ExecutionContext) deleteVar(name string) {
+	delete(ctx.Vars, name)
+	ctx.overlayTemplateVar(name, deletedVar{})
+}
+
+// overlayTemplateVar adds a variable change to the pending layer. Names that are shadowed by operation
+// outputs or built-in template variables invalidate the cache instead.
+func (ctx *ExecutionContext) overlayTemplateVar(name string, value interface{}) {
+	ctx.OperationMutex.RLock()
+	_, isOutput := ctx.OperationOutputs[name]
+	_, isData := ctx.OperationData[name]
+	ctx.OperationMutex.RUnlock()
+
+	scope := &ctx.scope
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	if isOutput || isData || isBuiltinTemplateVar(name) || scope.vars == nil || scope.builtVersion != scope.version {
+		scope.version++
+		return
+	}
+
+	if scope.pending == nil {
+		scope.pending = make(map[string]interface{})
+	}
+	scope.pending[name] = value
+}
+
+// isBuiltinTemplateVar reports whether a name is one of the variables templateVars adds itself
+func isBuiltinTemplateVar(name string) bool {
+	switch name {
+	case "context", "operationOutputs", "operationResults", "allTasksComplete", "anyTasksFailed",
+		"duration_ms", "duration_s", "duration_fmt", "duration_ms_fmt":
+		return true
+	default:
+		return false
+	}
+}
+
+// invalidateTemplateVars marks the cached template variables as stale. It must be called after
+// changing Vars, OperationOutputs or OperationData directly.
+func (ctx *ExecutionContext) invalidateTemplateVars() {
+	ctx.scope.mu.Lock()
+	ctx.scope.version++
+	ctx.scope.pending = nil
+	ctx.scope.mu.Unlock()
+}
+
+// cachedTemplateVars returns the shared template variables, rebuilding them only when the context has changed.
+// The map is built without holding the scope lock, since building takes the operation and background locks.
+func (ctx *ExecutionContext) cachedTemplateVars(build func() map[string]interface{}) map[string]interface{} {
+	duration := ctx.getCurrentLoopDuration()
+	allComplete := ctx.allTasksComplete()
+	anyFailed := ctx.anyTasksFailed()
+
+	scope := &ctx.scope
+	scope.mu.Lock()
+	// leaving every loop removes the duration variables, which needs a rebuild
+	if scope.vars != nil && scope.builtVersion == scope.version && (duration > 0 || scope.duration == 0) &&
+		scope.allComplete == allComplete && scope.anyFailed == anyFailed {
+		if scope.duration != duration {
+			// loop durations change every iteration, so they are overlaid rather than rebuilding every variable
+			scope.pending = overlayLoopDurationVars(scope.pending, duration)
+			scope.duration = duration
+		}
+		if len(scope.pending) > 0 {
+			scope.vars = mergePendingVars(scope.vars, scope.pending)
+			scope.pending = nil
+		}
+		vars := scope.vars
+		scope.mu.Unlock()
+		return vars
+	}
+	version := scope.version
+	scope.mu.Unlock()
+
+	vars := build()
+
+	scope.mu.Lock()
+	if scope.version == version {
+		// pending changes made while building are kept and merged on the next read
+		scope.vars = vars
+		scope.builtVersion = version
+		scope.duration = duration
+		scope.allComplete = allComplete
+		scope.anyFailed = anyFailed
+	}
+	scope.mu.Unlock()
+
+	return vars
+}
+
+// withTemplateVars returns a copy of vars with extra values added, leaving the shared map untouched
+func withTemplateVars(vars map[string]interface{}, extra map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(vars)+len(extra))
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// mergePendingVars applies pending variable changes to a copy of the cached variables
+func mergePendingVars(vars map[string]interface{}, pending map[string]interface{}) map[string]interface{} {
+	merged := withTemplateVars(vars, nil)
+	for name, value := range pending {
+		if _, deleted := value.(deletedVar); deleted {
+			delete(merged, name)
+		} else {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// overlayLoopDurationVars adds the duration variables of a running loop to the pending layer
+func overlayLoopDurationVars(pending map[string]interface{}, duration time.Duration) map[string]interface{} {
+	if pending == nil {
+		pending = make(map[string]interface{})
+	}
+	for name, value := range loopDurationVars(duration) {
+		pending[name] = value
+	}
+	return pending
+}
+
+// parse returns the parsed template for a source string, parsing and caching it on first use
+func (c *templateCache) parse(tmplStr string, funcs template.FuncMap, strict bool) (*template.Template, error) {
+	key := tmplStr
+	if strict {
+		key = "strict\x00" + tmplStr
+	}
+
+	c.mu.RLock()
+	tmpl, ok := c.templates[key]
+	c.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.New("template").Funcs(funcs).Parse(tmplStr)
+	if err != nil {
+		return nil, err
+	}
+	if strict {
+		tmpl.Option("missingkey=error")
+	}
+
+	c.mu.Lock()
+	if c.templates == nil || len(c.templates) >= maxCachedTemplates {
+		c.templates = make(map[string]*template.Template)
+	}
+	c.templates[key] = tmpl
+	c.mu.Unlock()
+
+	return tmpl, nil
+}
+
+// hasTemplateActions reports whether a string contains template actions and needs to be parsed at all
+func hasTemplateActions(s string) bool {
+	return strings.Contains(s, "{{")
+}
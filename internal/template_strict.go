@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agnivade/levenshtein"
+)
+
+// missingKeyPattern matches the text/template error raised by missingkey=error
+var missingKeyPattern = regexp.MustCompile(`:(\d+):(\d+): executing "[^"]*" at <([^>]*)>: map has no entry for key "([^"]*)"`)
+
+// maxTemplateSuggestions limits how many close matches are offered for an undefined variable
+const maxTemplateSuggestions = 3
+
+// undefinedVariableError reports a template reference to a variable that does not exist in strict mode
+type undefinedVariableError struct {
+	Key         string
+	Expression  string
+	Line        int
+	Column      int
+	Source      string
+	Operation   string // set once the error has been attributed to an operation
+	Suggestions []string
+}
+
+func (e *undefinedVariableError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("undefined variable %q", e.Key))
+	if e.Expression != "" && e.Expression != "."+e.Key {
+		sb.WriteString(fmt.Sprintf(" in <%s>", e.Expression))
+	}
+	sb.WriteString(fmt.Sprintf(" at line %d, column %d", e.Line, e.Column+1))
+
+	if e.Source != "" {
+		sb.WriteString(fmt.Sprintf("\n  %s\n  %s^", e.Source, strings.Repeat(" ", e.Column)))
+	}
+	if len(e.Suggestions) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  did you mean: %s?", strings.Join(e.Suggestions, ", ")))
+	}
+
+	return sb.String()
+}
+
+// newUndefinedVariableError converts a missingkey=error execution error into an undefinedVariableError.
+// Other errors are returned unchanged.
+func newUndefinedVariableError(err error, tmplStr string, vars map[string]interface{}, ctx *ExecutionContext) error {
+	matches := missingKeyPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+
+	line, _ := strconv.Atoi(matches[1])
+	column, _ := strconv.Atoi(matches[2])
+	expression, key := matches[3], matches[4]
+
+	source := ""
+	if lines := strings.Split(tmplStr, "\n"); line >= 1 && line <= len(lines) {
+		source = lines[line-1]
+	}
+	if column > len(source) {
+		column = len(source)
+	}
+
+	return &undefinedVariableError{
+		Key:         key,
+		Expression:  expression,
+		Line:        line,
+		Column:      column,
+		Source:      source,
+		Suggestions: suggestTemplateKeys(key, candidateTemplateKeys(expression, key, vars, ctx)),
+	}
+}
+
+// candidateTemplateKeys lists the names a missing key could have been meant as. For a nested reference such as
+// .build.stauts the keys of .build are used, otherwise the template variables and the recipe's operation IDs.
+func candidateTemplateKeys(expression, key string, vars map[string]interface{}, ctx *ExecutionContext) []string {
+	path := strings.TrimPrefix(expression, ".")
+	if parent := strings.TrimSuffix(path, "."+key); parent != path && !strings.HasPrefix(expression, "$") {
+		if value, ok := lookupVarPath(vars, parent); ok {
+			return mapKeys(value)
+		}
+	}
+
+	var candidates []string
+	for name := range vars {
+		candidates = append(candidates, name)
+	}
+	if ctx != nil {
+		candidates = append(candidates, ctx.operationIDs...)
+	}
+	return candidates
+}
+
+// suggestTemplateKeys returns the candidates closest to a missing key by edit distance
+func suggestTemplateKeys(key string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	seen := make(map[string]bool)
+	var matches []match
+	for _, name := range candidates {
+		if seen[name] || name == key {
+			continue
+		}
+		seen[name] = true
+
+		distance := levenshtein.ComputeDistance(strings.ToLower(key), strings.ToLower(name))
+		if distance <= maxDistance {
+			matches = append(matches, match{name: name, distance: distance})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var suggestions []string
+	for i := 0; i < len(matches) && i < maxTemplateSuggestions; i++ {
+		suggestions = append(suggestions, matches[i].name)
+	}
+	return suggestions
+}
+
+// annotateUndefinedVariable prefixes an undefined variable error with the operation it occurred in. Only the
+// innermost operation is recorded when operations are nested.
+func annotateUndefinedVariable(err error, operation string) error {
+	var undefinedErr *undefinedVariableError
+	if !errors.As(err, &undefinedErr) || undefinedErr.Operation != "" {
+		return err
+	}
+	undefinedErr.Operation = operation
+	return fmt.Errorf("operation '%s': %w", operation, err)
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// regexMatch reports whether the input matches the pattern
+func regexMatch(input, pattern string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(input), nil
+}
+
+// regexFind returns the first match of the pattern in the input
+func regexFind(input, pattern string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(input), nil
+}
+
+// regexReplace replaces all matches of the pattern, expanding $1 style references in the replacement
+func regexReplace(input, pattern, replacement string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(input, replacement), nil
+}
+
+// regexSplit splits the input around every match of the pattern
+func regexSplit(input, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.Split(input, -1), nil
+}
+
+// sha256Sum returns the hex encoded SHA-256 digest of the input
+func sha256Sum(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
+
+// md5Sum returns the hex encoded MD5 digest of the input
+func md5Sum(input string) string {
+	sum := md5.Sum([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
+
+// base64Encode encodes the input using standard base64
+func base64Encode(input string) string {
+	return base64.StdEncoding.EncodeToString([]byte(input))
+}
+
+// base64Decode decodes standard base64 input
+func base64Decode(input string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(input))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// titleCase upper-cases the first letter of every word
+func titleCase(input string) string {
+	runes := []rune(input)
+	startOfWord := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if startOfWord {
+				runes[i] = unicode.ToUpper(r)
+			}
+			startOfWord = false
+		} else {
+			startOfWord = true
+		}
+	}
+	return string(runes)
+}
+
+// indentLines prefixes every line of the input with the given number of spaces
+func indentLines(input string, spaces interface{}) string {
+	pad := strings.Repeat(" ", int(toFloat64(spaces)))
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapText wraps each paragraph of the input at the given width without breaking words
+func wrapText(input string, width interface{}) string {
+	limit := int(toFloat64(width))
+	if limit <= 0 {
+		return input
+	}
+
+	var result []string
+	for _, paragraph := range strings.Split(input, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			result = append(result, "")
+			continue
+		}
+
+		line := words[0]
+		for _, word := range words[1:] {
+			if len(line)+1+len(word) > limit {
+				result = append(result, line)
+				line = word
+				continue
+			}
+			line += " " + word
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// quoteString wraps the input in double quotes, escaping it like a Go string literal
+func quoteString(input interface{}) string {
+	return strconv.Quote(fmt.Sprintf("%v", input))
+}
+
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes the input so a POSIX shell treats it as a single literal word
+func shellQuote(input interface{}) string {
+	s := fmt.Sprintf("%v", input)
+	if s == "" {
+		return "''"
+	}
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
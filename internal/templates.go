@@ -2,18 +2,27 @@ package internal
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 	"text/template"
 	"time"
+
+	"github.com/google/uuid"
 )
 
-// templateVars creates a map of variables available for template rendering
+// templateVars returns the variables available for template rendering. The map is shared between
+// calls until the context changes, so callers must not modify it.
 func (ctx *ExecutionContext) templateVars() map[string]interface{} {
+	return ctx.cachedTemplateVars(ctx.buildTemplateVars)
+}
+
+// buildTemplateVars creates a map of variables available for template rendering
+func (ctx *ExecutionContext) buildTemplateVars() map[string]interface{} {
 	vars := make(map[string]interface{})
 
 	for k, v := range ctx.Vars {
@@ -24,6 +33,9 @@ func (ctx *ExecutionContext) templateVars() map[string]interface{} {
 	for opID, output := range ctx.OperationOutputs {
 		vars[opID] = output
 	}
+	for opID, value := range ctx.OperationData {
+		vars[opID] = value
+	}
 	operationOutputsCopy := make(map[string]string)
 	for k, v := range ctx.OperationOutputs {
 		operationOutputsCopy[k] = v
@@ -42,16 +54,26 @@ func (ctx *ExecutionContext) templateVars() map[string]interface{} {
 		duration = ctx.LoopStack[ctx.CurrentLoopIdx].Duration
 	}
 
-	if duration > 0 {
-		vars["duration_ms"] = fmt.Sprintf("%d", duration.Milliseconds())
-		vars["duration_s"] = fmt.Sprintf("%d", int(duration.Seconds()))
-		vars["duration_fmt"] = formatDuration(duration)
-		vars["duration_ms_fmt"] = formatDurationWithMs(duration)
+	for name, value := range loopDurationVars(duration) {
+		vars[name] = value
 	}
 
 	return vars
 }
 
+// loopDurationVars returns the duration variables for a loop duration, or none when no loop is running
+func loopDurationVars(duration time.Duration) map[string]interface{} {
+	if duration <= 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"duration_ms":     fmt.Sprintf("%d", duration.Milliseconds()),
+		"duration_s":      fmt.Sprintf("%d", int(duration.Seconds())),
+		"duration_fmt":    formatDuration(duration),
+		"duration_ms_fmt": formatDurationWithMs(duration),
+	}
+}
+
 // Template functions are organized by category
 var templateFuncs = buildTemplateFunctions()
 
@@ -62,6 +84,9 @@ func buildTemplateFunctions() template.FuncMap {
 	stringFunctions(funcs)
 	mathFunctions(funcs)
 	formattingFunctions(funcs)
+	dataFunctions(funcs)
+	dateFunctions(funcs)
+	semverFunctions(funcs)
 	for name, fn := range TableFuncMap() {
 		funcs[name] = fn
 	}
@@ -84,9 +109,32 @@ func stringFunctions(funcs template.FuncMap) {
 	funcs["overlap"] = findOverlap
 	funcs["cut"] = cutFields
 	funcs["exec"] = execCommand
+	funcs["execOrFail"] = execOrFailCommand
 	funcs["count"] = count
 	funcs["list"] = createList
 	funcs["raw"] = encodeEscapes
+	funcs["upper"] = strings.ToUpper
+	funcs["lower"] = strings.ToLower
+	funcs["title"] = titleCase
+	funcs["indent"] = indentLines
+	funcs["wrap"] = wrapText
+	funcs["quote"] = quoteString
+	funcs["shellQuote"] = shellQuote
+	funcs["regexMatch"] = regexMatch
+	funcs["regexFind"] = regexFind
+	funcs["regexReplace"] = regexReplace
+	funcs["regexSplit"] = regexSplit
+	funcs["sha256"] = sha256Sum
+	funcs["md5"] = md5Sum
+	funcs["b64enc"] = base64Encode
+	funcs["b64dec"] = base64Decode
+	funcs["uuid"] = func() string {
+		return uuid.New().String()
+	}
+	funcs["base"] = filepath.Base
+	funcs["dir"] = filepath.Dir
+	funcs["ext"] = filepath.Ext
+	funcs["absPath"] = filepath.Abs
 }
 
 // mathFunctions adds mathematical functions to the template function map
@@ -224,10 +272,13 @@ func extendTemplateFuncs(baseFuncs template.FuncMap, ctx *ExecutionContext) temp
 		newFuncs[k] = v
 	}
 
+	newFuncs["exec"] = execFunc(ctx)
+	newFuncs["execOrFail"] = execOrFailFunc(ctx)
 	newFuncs["bgTaskStatus"] = backgroundTaskStatusFunc(ctx)
 	newFuncs["bgTaskComplete"] = backgroundTaskCompleteFunc(ctx)
 	newFuncs["bgTaskFailed"] = backgroundTaskFailedFunc(ctx)
 	newFuncs["taskStatusMessage"] = backgroundTaskMessageFunc(ctx)
+	newFuncs["bgTaskTail"] = backgroundTaskTailFunc(ctx)
 
 	return newFuncs
 }
@@ -311,6 +362,20 @@ func backgroundTaskMessageFunc(ctx *ExecutionContext) func(string, string, strin
 	}
 }
 
+// backgroundTaskTailFunc returns a function to read the last n lines of a task's output while it runs
+func backgroundTaskTailFunc(ctx *ExecutionContext) func(string, interface{}) string {
+	return func(taskID string, n interface{}) string {
+		ctx.BackgroundMutex.RLock()
+		task, exists := ctx.BackgroundTasks[taskID]
+		ctx.BackgroundMutex.RUnlock()
+
+		if !exists || task.LogPath == "" {
+			return ""
+		}
+		return tailLogFile(task.LogPath, int(toFloat64(n)))
+	}
+}
+
 // JoinArray joins array elements into a string with the specified separator
 func JoinArray(arr interface{}, sep string) string {
 	switch v := arr.(type) {
@@ -389,15 +454,6 @@ func cutFields(input, delimiter string, field int) string {
 	return strings.Join(result, "\n")
 }
 
-// execCommand executes a shell command and returns its output
-func execCommand(cmd string) string {
-	output, err := exec.Command("sh", "-c", cmd).Output()
-	if err != nil {
-		return ""
-	}
-	return string(output)
-}
-
 // count returns the number of items in an array or lines in a string
 func count(val interface{}) int {
 	switch v := val.(type) {
@@ -429,28 +485,43 @@ func createList(items ...interface{}) []interface{} {
 // renderTemplate parses and executes a Go template with the provided variables
 func renderTemplate(tmplStr string, vars map[string]interface{}) (string, error) {
 	funcs := templateFuncs
-	if ctxVal, ok := vars["context"]; ok {
-		if ctx, ok := ctxVal.(*ExecutionContext); ok && ctx != nil {
-			if ctx.templateFuncs == nil {
-				ctx.templateFuncs = extendTemplateFuncs(templateFuncs, ctx)
-			}
-			funcs = ctx.templateFuncs
+	ctx, _ := vars["context"].(*ExecutionContext)
+	if ctx != nil {
+		if ctx.templateFuncs == nil {
+			ctx.templateFuncs = extendTemplateFuncs(templateFuncs, ctx)
 		}
+		funcs = ctx.templateFuncs
 	}
 
-	tmpl, err := template.New("template").Funcs(funcs).Parse(tmplStr)
-	if err != nil {
-		return "", fmt.Errorf("template parse error: %w", err)
-	}
+	result := tmplStr
+	if hasTemplateActions(tmplStr) {
+		cache := defaultTemplateCache
+		if ctx != nil {
+			cache = &ctx.templates
+		}
+
+		strict := ctx != nil && ctx.Strict
+		tmpl, err := cache.parse(tmplStr, funcs, strict)
+		if err != nil {
+			return "", fmt.Errorf("template parse error: %w", err)
+		}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, vars); err != nil {
-		return "", fmt.Errorf("template execution error: %w", err)
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			var execErr *templateExecError
+			if errors.As(err, &execErr) {
+				return "", execErr
+			}
+			if strict {
+				return "", newUndefinedVariableError(err, tmplStr, vars, ctx)
+			}
+			return "", fmt.Errorf("template execution error: %w", err)
+		}
+		result = buf.String()
 	}
 
-	result := buf.String()
 	result = handleDefaultEmpty(result)
-	result = escapeEchoInput(result)
+	result = escapeEchoInput(tmplStr, result)
 	result = decodeEscapes(result)
 
 	return result, nil
@@ -458,15 +529,20 @@ func renderTemplate(tmplStr string, vars map[string]interface{}) (string, error)
 
 // transformOutput applies a template transformation to the given output
 func transformOutput(output, transform string, ctx *ExecutionContext) (string, error) {
-	vars := ctx.templateVars()
-	vars["input"] = output
-	vars["output"] = output
+	vars := withTemplateVars(ctx.templateVars(), map[string]interface{}{
+		"input":  output,
+		"output": output,
+	})
 
 	return renderTemplate(transform, vars)
 }
 
-// escapeEchoInput handles special characters within an echo string
-func escapeEchoInput(input string) string {
+// escapeEchoInput handles special characters within an echo string. Only echo strings that the template itself wraps in
+// single quotes are escaped, so quoting produced by template functions such as shellQuote is left as it is.
+func escapeEchoInput(tmplStr, input string) string {
+	if !strings.HasPrefix(tmplStr, "echo '") || !strings.HasSuffix(tmplStr, "'") {
+		return input
+	}
 	if len(input) > 6 && strings.HasPrefix(input, "echo '") && strings.HasSuffix(input, "'") {
 		return fmt.Sprintf("echo '%s'", strings.ReplaceAll(input[6:len(input)-1], "'", "'\\''"))
 	}
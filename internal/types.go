@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 )
@@ -14,14 +16,19 @@ type File struct {
 
 // Recipe defines a Shef recipe with its metadata and operations
 type Recipe struct {
-	Name        string                 `yaml:"name"`
-	Description string                 `yaml:"description"`
-	Category    string                 `yaml:"category,omitempty"`
-	Author      string                 `yaml:"author,omitempty"`
-	Help        string                 `yaml:"help,omitempty"`
-	Vars        map[string]interface{} `yaml:"vars,omitempty"`
-	Workdir     string                 `yaml:"workdir,omitempty"`
-	Operations  []Operation            `yaml:"operations"`
+	Name            string                 `yaml:"name"`
+	Description     string                 `yaml:"description"`
+	Category        string                 `yaml:"category,omitempty"`
+	Author          string                 `yaml:"author,omitempty"`
+	Help            string                 `yaml:"help,omitempty"`
+	Vars            map[string]interface{} `yaml:"vars,omitempty"`
+	Workdir         string                 `yaml:"workdir,omitempty"`
+	MaxBackground   int                    `yaml:"max_background,omitempty"`
+	Stdin           string                 `yaml:"stdin,omitempty"`
+	Strict          bool                   `yaml:"strict,omitempty"`
+	RememberPrompts bool                   `yaml:"remember_prompts,omitempty"`
+	DryRun          bool                   `yaml:"-"`
+	Operations      []Operation            `yaml:"operations"`
 }
 
 // Operation defines a single executable step in a recipe
@@ -44,8 +51,16 @@ type Operation struct {
 	UserShell                  bool                   `yaml:"user_shell,omitempty"`
 	Prompts                    []Prompt               `yaml:"prompts,omitempty"`
 	Break                      bool                   `yaml:"break,omitempty"`
+	Continue                   bool                   `yaml:"continue,omitempty"`
 	Exit                       bool                   `yaml:"exit,omitempty"`
 	Cleanup                    interface{}            `yaml:"cleanup,omitempty"`
+	WaitFor                    []string               `yaml:"wait_for,omitempty"`
+	Cancel                     string                 `yaml:"cancel,omitempty"`
+	Timeout                    string                 `yaml:"timeout,omitempty"`
+	ExecTimeout                string                 `yaml:"exec_timeout,omitempty"`
+	FailRecipe                 bool                   `yaml:"fail_recipe,omitempty"`
+	Attach                     string                 `yaml:"attach,omitempty"`
+	Stdin                      interface{}            `yaml:"stdin,omitempty"`
 	Workdir                    string                 `yaml:"workdir,omitempty"`
 	ComponentInstanceID        string                 `yaml:"-"`
 	IsComponentOutputCollector bool                   `yaml:"-"`
@@ -66,19 +81,39 @@ type Prompt struct {
 	MaxValue        int               `yaml:"max_value,omitempty"`
 	Required        bool              `yaml:"required,omitempty"`
 	FileExtensions  []string          `yaml:"file_extensions,omitempty"`
+	Multiple        bool              `yaml:"multiple,omitempty"`
 	MultipleLimit   int               `yaml:"multiple_limit,omitempty"`
 	EditorCmd       string            `yaml:"editor_cmd,omitempty"`
+	Format          string            `yaml:"format,omitempty"`
+	MinDate         string            `yaml:"min_date,omitempty"`
+	MaxDate         string            `yaml:"max_date,omitempty"`
 	HelpText        string            `yaml:"help_text,omitempty"`
 	Validators      []PromptValidator `yaml:"validators,omitempty"`
+	Condition       string            `yaml:"condition,omitempty"`
+	Remember        *bool             `yaml:"remember,omitempty"`
+	Secret          bool              `yaml:"secret,omitempty"`
+	Columns         []string          `yaml:"columns,omitempty"`
+	ValueField      string            `yaml:"value_field,omitempty"`
+	TableStyle      string            `yaml:"table_style,omitempty"`
+	Fields          []Prompt          `yaml:"fields,omitempty"`
+	Checks          []FormCheck       `yaml:"checks,omitempty"`
+}
+
+// FormCheck is a cross-field rule that must hold before a form prompt is submitted
+type FormCheck struct {
+	Condition string `yaml:"condition"`
+	Message   string `yaml:"message"`
 }
 
 // PromptValidator defines validation rules for prompt inputs
 type PromptValidator struct {
-	Type    string `yaml:"type"`
-	Pattern string `yaml:"pattern,omitempty"`
-	Message string `yaml:"message,omitempty"`
-	Min     int    `yaml:"min,omitempty"`
-	Max     int    `yaml:"max,omitempty"`
+	Type    string   `yaml:"type"`
+	Pattern string   `yaml:"pattern,omitempty"`
+	Message string   `yaml:"message,omitempty"`
+	Min     int      `yaml:"min,omitempty"`
+	Max     int      `yaml:"max,omitempty"`
+	Options []string `yaml:"options,omitempty"`
+	Command string   `yaml:"command,omitempty"`
 }
 
 // BackgroundTaskStatus represents the current state of a background task
@@ -86,10 +121,11 @@ type BackgroundTaskStatus string
 
 // Background task status constants.
 const (
-	TaskPending  BackgroundTaskStatus = "pending"
-	TaskComplete BackgroundTaskStatus = "complete"
-	TaskFailed   BackgroundTaskStatus = "failed"
-	TaskUnknown  BackgroundTaskStatus = "unknown"
+	TaskPending   BackgroundTaskStatus = "pending"
+	TaskComplete  BackgroundTaskStatus = "complete"
+	TaskFailed    BackgroundTaskStatus = "failed"
+	TaskCancelled BackgroundTaskStatus = "cancelled"
+	TaskUnknown   BackgroundTaskStatus = "unknown"
 )
 
 // BackgroundTask represents an asynchronous command execution
@@ -99,6 +135,9 @@ type BackgroundTask struct {
 	Status  BackgroundTaskStatus
 	Output  string
 	Error   string
+	LogPath string
+	cancel  context.CancelFunc
+	done    chan struct{}
 }
 
 // ExecutionContext maintains state during recipe execution
@@ -106,16 +145,30 @@ type ExecutionContext struct {
 	Data                          string
 	Vars                          map[string]interface{}
 	OperationOutputs              map[string]string
+	OperationData                 map[string]interface{}
 	OperationResults              map[string]bool
 	ProgressMode                  bool
 	templateFuncs                 template.FuncMap
 	BackgroundTasks               map[string]*BackgroundTask
 	BackgroundMutex               sync.RWMutex
 	BackgroundWg                  sync.WaitGroup
+	BackgroundSlots               chan struct{}
+	BackgroundError               error
 	OperationMutex                sync.RWMutex
 	LoopStack                     []*LoopContext
 	CurrentLoopIdx                int
 	ExecutedOperationsByComponent map[string][]string
+	RunID                         string
+	RunDir                        string
+	DefaultStdin                  string
+	Strict                        bool
+	DryRun                        bool
+	operationIDs                  []string
+	scope                         templateScope
+	templates                     templateCache
+	execOptions                   atomic.Pointer[execOptions]
+	promptAnswers                 map[string]interface{}
+	promptMemory                  *promptMemory
 }
 
 // ComponentInput defines an input parameter for a component
@@ -138,9 +191,11 @@ type Component struct {
 
 // LoopContext tracks state for a specific loop
 type LoopContext struct {
-	ID        string
-	StartTime time.Time
-	Duration  time.Duration
-	Type      string
-	Depth     int
+	ID               string
+	StartTime        time.Time
+	Duration         time.Duration
+	Type             string
+	Depth            int
+	OnError          string
+	FailedIterations []int
 }
@@ -66,15 +66,26 @@ func formatDurationWithMs(d time.Duration) string {
 	return fmt.Sprintf("%s.%03d", baseFormat, milliseconds)
 }
 
+// parseDurationWithDays parses a Go duration, additionally accepting whole days such as 7d
+func parseDurationWithDays(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if d, err := time.ParseDuration(days + "h"); err == nil {
+			return d * 24, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
 // updateDurationVars updates duration-related variables in the execution context
 func updateDurationVars(ctx *ExecutionContext, startTime time.Time) {
 	elapsed := time.Since(startTime)
 
-	ctx.Vars["duration_ms"] = fmt.Sprintf("%d", elapsed.Milliseconds())
-	ctx.Vars["duration_s"] = fmt.Sprintf("%d", int(elapsed.Seconds()))
+	ctx.setVar("duration_ms", fmt.Sprintf("%d", elapsed.Milliseconds()))
+	ctx.setVar("duration_s", fmt.Sprintf("%d", int(elapsed.Seconds())))
 
-	ctx.Vars["duration_fmt"] = formatDuration(elapsed)
-	ctx.Vars["duration_ms_fmt"] = formatDurationWithMs(elapsed)
+	ctx.setVar("duration_fmt", formatDuration(elapsed))
+	ctx.setVar("duration_ms_fmt", formatDurationWithMs(elapsed))
 }
 
 // parseOptionsFromOutput converts multi-line output to a string slice of options
@@ -377,26 +388,24 @@ func encodeEscapes(input interface{}) string {
 	return result
 }
 
+// escapeDecoder converts the placeholders written by encodeEscapes back to backslash sequences
+var escapeDecoder = strings.NewReplacer(
+	"<escape-n>", `\\n`,
+	"<escape-t>", `\\t`,
+	"<escape-r>", `\\r`,
+	"<escape-a>", `\\a`,
+	"<escape-b>", `\\b`,
+	"<escape-f>", `\\f`,
+	"<escape-v>", `\\v`,
+	"<escape-backslash>", `\\\\`,
+	"<escape-quote>", `\\'`,
+	"<escape-dquote>", `\\"`,
+)
+
 // decodeEscapes converts back to original backslash+character sequences
 func decodeEscapes(input string) string {
-	escapeMap := map[string]string{
-		"<escape-n>":         `\\n`,
-		"<escape-t>":         `\\t`,
-		"<escape-r>":         `\\r`,
-		"<escape-a>":         `\\a`,
-		"<escape-b>":         `\\b`,
-		"<escape-f>":         `\\f`,
-		"<escape-v>":         `\\v`,
-		"<escape-backslash>": `\\\\`,
-		"<escape-quote>":     `\\'`,
-		"<escape-dquote>":    `\\"`,
-	}
-
-	result := input
-	for replacement, escape := range escapeMap {
-		re := regexp.MustCompile(replacement)
-		result = re.ReplaceAllString(result, escape)
+	if !strings.Contains(input, "<escape-") {
+		return input
 	}
-
-	return result
+	return escapeDecoder.Replace(input)
 }